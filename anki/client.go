@@ -53,13 +53,17 @@ func NewAnkiClient(deckName string) (*AnkiClient, error) {
 }
 
 func (c *AnkiClient) RemoveUnusedIDs(keepIds []int64) error {
+	return c.removeUnusedIDs(modelName, keepIds)
+}
+
+func (c *AnkiClient) removeUnusedIDs(model string, keepIds []int64) error {
 	removeIds := []int64{}
 	keepIdsSet := set.New(set.NonThreadSafe)
 	for _, id := range keepIds {
 		keepIdsSet.Add(id)
 	}
 
-	results, restErr := c.Connect.Notes.Get(fmt.Sprintf("note:Movie deck:%s", c.deckName))
+	results, restErr := c.Connect.Notes.Get(fmt.Sprintf(`"note:%s" deck:%s`, model, c.deckName))
 	if restErr != nil {
 		return RestErr(*restErr)
 	}
@@ -142,6 +146,10 @@ func resultNotesToMovieNote(result ankiconnect.ResultNotesInfo) (int64, MovieNot
 		note.Popularity = float32(popularity)
 	}
 
+	if value, ok := result.Fields[moviePeople]; ok {
+		note.people = value.Value
+	}
+
 	if value, ok := result.Fields[movieReleaseDate]; !ok {
 		return 0, MovieNote{}, errors.Wrap(ErrNoteInvalid, "release date missing")
 	} else if value.Value != "" {
@@ -164,6 +172,7 @@ var (
 	TagCinematographer        = "cinematographer"
 	TagWriter                 = "writer"
 	TagGenres                 = "genres"
+	TagConnection             = "connection"
 )
 
 func (t Tags) GetOne(key string) (string, bool) {
@@ -16,6 +16,11 @@ func normalizeMaybeClozeSlice(slice []MaybeCloze) []MaybeCloze {
 	normalized := make([]MaybeCloze, len(slice))
 	copy(normalized, slice)
 
+	// Characters aren't in the tags, notes read from Anki don't have them
+	for i := range normalized {
+		normalized[i].Character = ""
+	}
+
 	// Sort using a custom comparator
 	sort.Slice(normalized, func(i, j int) bool {
 		if normalized[i].IsCloze != normalized[j].IsCloze {
@@ -125,6 +130,17 @@ func (n MovieNote) IsEqual(other MovieNote) bool {
 		return false
 	}
 
+	// Compare the rendered People field, which also holds what isn't in the
+	// tags, like characters. Only notes read from Anki have it, two local
+	// notes may list people in any order.
+	if n.people != "" || other.people != "" {
+		if n.renderedPeople() != other.renderedPeople() {
+			fmt.Println("People not equal")
+			fmt.Printf("Got: %q\nWant: %q\n", n.renderedPeople(), other.renderedPeople())
+			return false
+		}
+	}
+
 	// Compare Pictures (assuming they need exact order comparison)
 	if !reflect.DeepEqual(n.Pictures, other.Pictures) && len(n.Pictures) != 0 || len(other.Pictures) != 0 {
 		fmt.Println("Pictures not equal")
@@ -134,3 +150,10 @@ func (n MovieNote) IsEqual(other MovieNote) bool {
 
 	return true
 }
+
+func (n MovieNote) renderedPeople() string {
+	if n.people != "" {
+		return n.people
+	}
+	return n.PeopleField()
+}
@@ -58,6 +58,13 @@ func TestIsEqual(t *testing.T) {
 		Cinematograper: makeMaybeCloze("Cinematographer A"),
 	}
 
+	// As read back from Anki, which keeps the rendered People field
+	stored := note1
+	stored.people = note1.PeopleField()
+
+	withCharacters := note1
+	withCharacters.Cast = []MaybeCloze{{Content: "Actor A", Character: "Hero"}, {Content: "Actor B", Character: "Villain"}}
+
 	tests := []struct {
 		name     string
 		first    MovieNote
@@ -67,6 +74,8 @@ func TestIsEqual(t *testing.T) {
 		{"Identical notes", note1, note1, true},
 		{"Order doesn't matter", note1, note2, true},
 		{"Different genres", note1, note3, false},
+		{"Stored note", stored, note1, true},
+		{"Characters added", stored, withCharacters, false},
 	}
 
 	for _, test := range tests {
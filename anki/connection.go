@@ -0,0 +1,178 @@
+package anki
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JonasRothmann/ankiconnect"
+	"github.com/pkg/errors"
+	ankierrors "github.com/privatesquare/bkst-go-utils/utils/errors"
+)
+
+// ConnectionMovie is one of the movies of a ConnectionNote.
+type ConnectionMovie struct {
+	TMDbID      int
+	Title       string
+	ReleaseDate time.Time
+}
+
+func (m ConnectionMovie) String() string {
+	if m.ReleaseDate.IsZero() {
+		return m.Title
+	}
+	return fmt.Sprintf("%s (%s)", m.Title, m.ReleaseDate.Format("2006"))
+}
+
+// ConnectionNote asks what links two movies, answered by the people on both.
+type ConnectionNote struct {
+	NoteID *int64
+
+	// The movie with the lower TMDB ID
+	First ConnectionMovie
+	// The movie with the higher TMDB ID
+	Second ConnectionMovie
+	People []string
+}
+
+const (
+	connectionMovies      = "Movies"
+	connectionFirstMovie  = "First Movie"
+	connectionSecondMovie = "Second Movie"
+	connectionPeople      = "People"
+)
+
+const connectionModelName = "Movie Connection"
+
+// fields renders the fields of the note. Movies comes first as Anki checks
+// the first field for duplicates, and only the pair is unique.
+func (n ConnectionNote) fields() ankiconnect.Fields {
+	return ankiconnect.Fields{
+		connectionMovies:      fmt.Sprintf("%s & %s", n.First, n.Second),
+		connectionFirstMovie:  n.First.String(),
+		connectionSecondMovie: n.Second.String(),
+		connectionPeople:      strings.Join(n.People, ", "),
+	}
+}
+
+func (n ConnectionNote) tags() Tags {
+	return Tags{}.Set(TagConnection, fmt.Sprintf("%d-%d", n.First.TMDbID, n.Second.TMDbID))
+}
+
+// duplicateSuffix is added to the Movies field when another note already
+// has the same one, e.g. for two pairs of movies with the same titles.
+func (n ConnectionNote) duplicateSuffix() string {
+	return fmt.Sprintf(" %d-%d", n.First.TMDbID, n.Second.TMDbID)
+}
+
+// IsEqual reports whether an existing note already has the fields of n. A
+// Movies field made unique with the duplicate suffix still matches.
+func (n ConnectionNote) IsEqual(existing ankiconnect.ResultNotesInfo) bool {
+	for name, value := range n.fields() {
+		field, ok := existing.Fields[name]
+		if !ok {
+			return false
+		}
+		if name == connectionMovies {
+			field.Value = strings.TrimSuffix(field.Value, n.duplicateSuffix())
+		}
+		if field.Value != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *AnkiClient) AddConnectionNote(note ConnectionNote) (int64, error) {
+	if len(note.People) == 0 {
+		return 0, errors.Wrap(ErrNoteInvalid, "no people link the movies")
+	}
+
+	ankiNote := ankiconnect.Note{
+		DeckName:  c.deckName,
+		ModelName: connectionModelName,
+		Fields:    note.fields(),
+		Tags:      note.tags(),
+	}
+
+	var attempt int
+	var id int64
+	var restErr *ankierrors.RestErr
+
+	for attempt = 0; attempt < 3; attempt++ {
+		id, restErr = c.Connect.Notes.Add(ankiNote)
+		if restErr != nil {
+			if restErr.Error == "cannot create note because it is a duplicate" {
+				ankiNote.Fields[connectionMovies] = note.fields()[connectionMovies] + note.duplicateSuffix()
+			}
+			fmt.Println("retrying")
+		} else {
+			break
+		}
+	}
+
+	if restErr != nil {
+		return 0, errors.Wrapf(RestErr(*restErr), "error when adding note via ankiconnect: note: %+v", ankiNote)
+	}
+	if id == 0 {
+		return 0, errors.New("id zero value")
+	}
+
+	return id, nil
+}
+
+// UpsertConnectionNote adds the note, or updates the note of the same pair
+// of movies when its fields differ.
+func (c *AnkiClient) UpsertConnectionNote(note *ConnectionNote) (int64, error) {
+	result, restErr := c.Connect.Notes.Get(c.connectionQuery(*note))
+	if restErr != nil {
+		return 0, errors.Wrapf(RestErr(*restErr), "error when getting note via ankiconnect: %s", note.fields()[connectionMovies])
+	}
+
+	if len(*result) > 0 {
+		existing := (*result)[0]
+		if existing.NoteId == 0 {
+			return 0, errors.New("id zero value")
+		}
+		note.NoteID = &existing.NoteId
+
+		if note.IsEqual(existing) {
+			return existing.NoteId, nil
+		}
+
+		// Keeping the suffix keeps the note from becoming a duplicate
+		fields := note.fields()
+		if movies, ok := existing.Fields[connectionMovies]; ok && strings.HasSuffix(movies.Value, note.duplicateSuffix()) {
+			fields[connectionMovies] += note.duplicateSuffix()
+		}
+
+		fmt.Println("not identical - updating")
+		if _, restErr := c.Connect.Notes.Update(ankiconnect.UpdateNote{
+			Id:     existing.NoteId,
+			Fields: fields,
+			Tags:   note.tags(),
+		}); restErr != nil {
+			return 0, errors.Errorf("error when update note via ankiconnect: %s", restErr.Error)
+		}
+
+		return existing.NoteId, nil
+	}
+
+	noteID, err := c.AddConnectionNote(*note)
+	if err != nil {
+		return 0, errors.Errorf("failed to add connection note: %s", err)
+	}
+	note.NoteID = &noteID
+
+	return noteID, nil
+}
+
+// RemoveUnusedConnectionIDs removes the connection notes of the deck that
+// aren't in keepIds.
+func (c *AnkiClient) RemoveUnusedConnectionIDs(keepIds []int64) error {
+	return c.removeUnusedIDs(connectionModelName, keepIds)
+}
+
+func (c *AnkiClient) connectionQuery(note ConnectionNote) string {
+	return fmt.Sprintf(`"note:%s" deck:%s tag:%s`, connectionModelName, c.deckName, note.tags()[0])
+}
@@ -0,0 +1,63 @@
+package anki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JonasRothmann/ankiconnect"
+)
+
+func TestConnectionNoteIsEqual(t *testing.T) {
+	note := ConnectionNote{
+		First:  ConnectionMovie{TMDbID: 1, Title: "Movie A", ReleaseDate: time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Second: ConnectionMovie{TMDbID: 2, Title: "Movie B"},
+		People: []string{"Actor A", "Director A"},
+	}
+
+	stored := func(note ConnectionNote) ankiconnect.ResultNotesInfo {
+		result := ankiconnect.ResultNotesInfo{NoteId: 1, Fields: map[string]ankiconnect.FieldData{}}
+		for name, value := range note.fields() {
+			result.Fields[name] = ankiconnect.FieldData{Value: value}
+		}
+		return result
+	}
+
+	if fields := note.fields(); fields[connectionMovies] != "Movie A (1999) & Movie B" {
+		t.Errorf("unexpected Movies field %q", fields[connectionMovies])
+	}
+	if tags := note.tags(); len(tags) != 1 || tags[0] != "connection:1-2" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	fewerPeople := note
+	fewerPeople.People = []string{"Actor A"}
+
+	renamed := note
+	renamed.Second.Title = "Movie B Redux"
+
+	// Added with the duplicate suffix as another note had the same Movies
+	suffixed := stored(note)
+	suffixed.Fields[connectionMovies] = ankiconnect.FieldData{Value: "Movie A (1999) & Movie B 1-2"}
+
+	tests := []struct {
+		name     string
+		note     ConnectionNote
+		existing ankiconnect.ResultNotesInfo
+		expected bool
+	}{
+		{"Identical", note, stored(note), true},
+		{"Person removed", fewerPeople, stored(note), false},
+		{"Renamed", renamed, stored(note), false},
+		{"Duplicate suffix", note, suffixed, true},
+		{"Renamed with duplicate suffix", renamed, suffixed, false},
+		{"Missing field", note, ankiconnect.ResultNotesInfo{NoteId: 1}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := test.note.IsEqual(test.existing); result != test.expected {
+				t.Errorf("IsEqual(%+v) = %v; want %v", test.existing, result, test.expected)
+			}
+		})
+	}
+}
@@ -16,6 +16,9 @@ import (
 type MaybeCloze struct {
 	IsCloze bool
 	Content string
+	// Shown after Content, e.g. the character a cast member plays. It isn't
+	// kept in the tags.
+	Character string
 }
 
 type MovieNote struct {
@@ -33,6 +36,9 @@ type MovieNote struct {
 	Cinematograper []MaybeCloze
 
 	Pictures []ankiconnect.Picture
+
+	// The People field as read from Anki, empty for notes built locally
+	people string
 }
 
 const (
@@ -85,6 +91,7 @@ var peopleTemplate = template.Must(
     {{- else}}
       {{$person.Content}}
     {{- end}}
+    {{- with $person.Character}} ({{.}}){{end}}
   {{- end}}
 {{- end -}}
 
@@ -127,18 +134,13 @@ func (c *AnkiClient) AddMovieNote(note MovieNote) (int64, error) {
 		return 0, ErrNoCloze
 	}
 
-	var sb strings.Builder
-	if err := peopleTemplate.Execute(&sb, note); err != nil {
-		panic(err)
-	}
-
 	ankiNote := ankiconnect.Note{
 		DeckName:  c.deckName,
 		ModelName: modelName,
 		Fields: ankiconnect.Fields{
 			movieTitle:       note.MovieTitle,
 			movieReleaseDate: note.ReleaseDate.Format("2006"),
-			moviePeople:      sb.String(),
+			moviePeople:      note.PeopleField(),
 			movieGenres:      strings.Join(note.Genres, ", "),
 			movieImage:       picturesToField(note.Pictures),
 			moviePopularity:  strconv.FormatFloat(float64(note.Popularity), 'f', 2, 64),
@@ -220,17 +222,12 @@ func (c *AnkiClient) UpsertMovieNote(note *MovieNote) (int64, error) {
 			return id, nil
 		} else {
 			fmt.Println("not identical - updating")
-			var sb strings.Builder
-			if err := peopleTemplate.Execute(&sb, *note); err != nil {
-				panic(err)
-			}
-
 			id, err := c.Connect.Notes.Update(ankiconnect.UpdateNote{
 				Id: id,
 				Fields: ankiconnect.Fields{
 					movieTitle:       note.MovieTitle,
 					movieReleaseDate: note.ReleaseDate.Format("2006"),
-					moviePeople:      sb.String(),
+					moviePeople:      note.PeopleField(),
 					movieGenres:      strings.Join(note.Genres, ", "),
 					movieImage:       picturesToField(note.Pictures),
 					moviePopularity:  strconv.FormatFloat(float64(note.Popularity), 'f', 2, 64),
@@ -268,6 +265,15 @@ func (c *AnkiClient) UpsertMovieNote(note *MovieNote) (int64, error) {
 	}
 }
 
+// PeopleField renders the People field of the note.
+func (n MovieNote) PeopleField() string {
+	var sb strings.Builder
+	if err := peopleTemplate.Execute(&sb, n); err != nil {
+		panic(err)
+	}
+	return sb.String()
+}
+
 func (c *AnkiClient) ToQuery(note MovieNote) string {
 	return fmt.Sprintf("note:%s deck:%s tag:tmdb:%d", modelName, c.deckName, note.TMDbID)
 }
@@ -1,73 +1,323 @@
 package tmdbankigenerator
 
 import (
+	"container/list"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 )
 
+const cacheFileVersion = 1
+
+type CacheOptions struct {
+	// How long writes are held back after a Set, so a burst of Sets is
+	// written to disk once. Defaults to a second.
+	FlushInterval time.Duration
+	// Entries set without their own TTL expire after this, 0 keeps them
+	// until they are evicted
+	TTL time.Duration
+	// The least recently used entries are evicted beyond this many, 0 doesn't
+	// bound the cache
+	MaxEntries int
+}
+
+// Cache is a key-value store kept in memory and written to a JSON file in
+// the background. Writes replace the file atomically, so a crash leaves
+// either the old or the new content behind. Call Close to write the last
+// changes.
 type Cache[K comparable, T any] struct {
-	content  map[K]T
 	fileName string
-	mu       sync.Mutex
+	opts     CacheOptions
+
+	mu sync.Mutex
+	// Most recently used first
+	order   *list.List
+	entries map[K]*list.Element
+	// Incremented by every change, to tell whether the file is up to date
+	version uint64
 
 	fileLock sync.Mutex
+	written  uint64
+	writeErr error
+
+	changed chan struct{}
+	closed  chan struct{}
+	done    chan struct{}
+	close   sync.Once
+}
+
+type cacheItem[K comparable, T any] struct {
+	key     K
+	value   T
+	expires time.Time
+}
+
+type cacheFileEntry[T any] struct {
+	Value   T          `json:"value"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+type cacheFile[K comparable, T any] struct {
+	Version int                     `json:"version"`
+	Entries map[K]cacheFileEntry[T] `json:"entries"`
 }
 
+// NewCache loads the cache from fileName with the default options.
 func NewCache[K comparable, T any](fileName string) (*Cache[K, T], error) {
-	bytes, err := os.ReadFile(fileName)
+	return NewCacheWithOptions[K, T](fileName, CacheOptions{})
+}
+
+// NewCacheWithOptions loads the cache from fileName. A missing file is an
+// empty cache, the file is created on the first write.
+func NewCacheWithOptions[K comparable, T any](fileName string, opts CacheOptions) (*Cache[K, T], error) {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	c := &Cache[K, T]{
+		fileName: fileName,
+		opts:     opts,
+		order:    list.New(),
+		entries:  make(map[K]*list.Element),
+		changed:  make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("failed to read cache %s: %w", fileName, err)
+	}
+
+	go c.writeInBackground()
+
+	return c, nil
+}
+
+// load reads the cache file. Files holding a plain JSON object of values,
+// as written by earlier versions, are read too; their entries don't expire.
+func (c *Cache[K, T]) load() error {
+	bytes, err := os.ReadFile(c.fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if len(bytes) == 0 {
+		return nil
 	}
 
-	var content map[K]T
-	if err := json.Unmarshal(bytes, &content); err != nil {
-		return nil, err
+	var file cacheFile[K, T]
+	if err := json.Unmarshal(bytes, &file); err == nil && file.Version == cacheFileVersion {
+		now := time.Now()
+		for key, entry := range file.Entries {
+			item := &cacheItem[K, T]{key: key, value: entry.Value}
+			if entry.Expires != nil {
+				if !now.Before(*entry.Expires) {
+					continue
+				}
+				item.expires = *entry.Expires
+			}
+			c.entries[key] = c.order.PushBack(item)
+		}
+	} else {
+		var values map[K]T
+		if err := json.Unmarshal(bytes, &values); err != nil {
+			return err
+		}
+		for key, value := range values {
+			c.entries[key] = c.order.PushBack(&cacheItem[K, T]{key: key, value: value})
+		}
 	}
 
-	return &Cache[K, T]{
-		content:  content,
-		fileName: fileName,
-	}, nil
+	c.evict()
+	return nil
 }
 
 func (c *Cache[K, T]) Get(key K) (bool, T) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if value, ok := c.content[key]; ok == true {
-		return true, value
-	} else {
-		return false, value
+	var zero T
+
+	element, ok := c.entries[key]
+	if !ok {
+		return false, zero
+	}
+
+	item := element.Value.(*cacheItem[K, T])
+	if !item.expires.IsZero() && !time.Now().Before(item.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		c.version++
+		return false, zero
 	}
+
+	c.order.MoveToFront(element)
+	return true, item.value
 }
 
+// Set stores the value with the default TTL of the cache.
 func (c *Cache[K, T]) Set(key K, value T) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores the value until ttl has passed, 0 keeps it until it is
+// evicted.
+func (c *Cache[K, T]) SetWithTTL(key K, value T, ttl time.Duration) {
+	c.mu.Lock()
+
+	item := &cacheItem[K, T]{key: key, value: value}
+	if ttl > 0 {
+		item.expires = time.Now().Add(ttl)
+	}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = item
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(item)
+	}
+
+	c.evict()
+	c.version++
+	c.mu.Unlock()
+
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Cache[K, T]) Len() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.content[key] = value
+	return len(c.entries)
+}
 
-	go func() {
-		c.fileLock.Lock()
-		defer c.fileLock.Unlock()
+// evict drops the least recently used entries beyond MaxEntries. c.mu must
+// be held.
+func (c *Cache[K, T]) evict() {
+	if c.opts.MaxEntries <= 0 {
+		return
+	}
 
-		fi, err := os.OpenFile(c.fileName, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
-		if err != nil {
-			fmt.Printf("cache failed to open file: %s", err)
-			//return err
+	for len(c.entries) > c.opts.MaxEntries {
+		element := c.order.Back()
+		c.order.Remove(element)
+		delete(c.entries, element.Value.(*cacheItem[K, T]).key)
+	}
+}
+
+// Flush writes the changes since the last write to disk. It also returns
+// the error of a failed background write, if there was one.
+func (c *Cache[K, T]) Flush() error {
+	c.fileLock.Lock()
+	defer c.fileLock.Unlock()
+
+	c.mu.Lock()
+	version := c.version
+	if version == c.written {
+		c.mu.Unlock()
+		err := c.writeErr
+		c.writeErr = nil
+		return err
+	}
+
+	file := cacheFile[K, T]{
+		Version: cacheFileVersion,
+		Entries: make(map[K]cacheFileEntry[T], len(c.entries)),
+	}
+	now := time.Now()
+	for key, element := range c.entries {
+		item := element.Value.(*cacheItem[K, T])
+		entry := cacheFileEntry[T]{Value: item.value}
+		if !item.expires.IsZero() {
+			if !now.Before(item.expires) {
+				continue
+			}
+			entry.Expires = &item.expires
 		}
-		defer fi.Close()
+		file.Entries[key] = entry
+	}
+	bytes, err := json.Marshal(file)
+	c.mu.Unlock()
 
-		jsonContent, err := json.Marshal(c.content)
-		if err != nil {
-			fmt.Printf("cache failed to marshal json content for saving to disk: %s", err)
+	if err == nil {
+		err = writeFileAtomic(c.fileName, bytes)
+	}
+	if err != nil {
+		c.writeErr = nil
+		return fmt.Errorf("failed to write cache %s: %w", c.fileName, err)
+	}
+
+	c.written = version
+	return nil
+}
+
+// Close stops writing in the background and writes the last changes.
+func (c *Cache[K, T]) Close() error {
+	c.close.Do(func() {
+		close(c.closed)
+	})
+	<-c.done
+
+	return c.Flush()
+}
+
+func (c *Cache[K, T]) writeInBackground() {
+	defer close(c.done)
+
+	for {
+		select {
+		case <-c.changed:
+		case <-c.closed:
+			return
 		}
 
-		if _, err := fi.WriteString(string(jsonContent)); err != nil {
-			fmt.Printf("cache failed to write to file: %s", err)
-			//return err
+		select {
+		case <-time.After(c.opts.FlushInterval):
+		case <-c.closed:
+			return
 		}
-	}()
+
+		if err := c.Flush(); err != nil {
+			c.fileLock.Lock()
+			c.writeErr = err
+			c.fileLock.Unlock()
+		}
+	}
+}
+
+// writeFileAtomic writes to a temporary file next to fileName and renames it
+// over fileName, so readers never see a partially written file.
+func writeFileAtomic(fileName string, data []byte) error {
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
 }
@@ -1,6 +1,15 @@
 package tmdbankigenerator
 
-/*
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
 // createTestFile creates a file with initial JSON-encoded content in a temp directory.
 func createTestFile(t *testing.T, content any) string {
 	t.Helper()
@@ -20,52 +29,18 @@ func createTestFile(t *testing.T, content any) string {
 	return filePath
 }
 
-// parseLastJSONBlob reads the entire file, and returns the last valid JSON object
-// (because the file might contain multiple appended JSON blobs).
-func parseLastJSONBlob(t *testing.T, filePath string) map[string]any {
+// reopen loads the file of a closed cache into a new cache, to check what was
+// persisted.
+func reopen(t *testing.T, filePath string) *Cache[string, string] {
 	t.Helper()
 
-	data, err := os.ReadFile(filePath)
+	cache, err := NewCache[string, string](filePath)
 	if err != nil {
-		t.Fatalf("failed to read file: %v", err)
-	}
-
-	// The file may contain multiple JSON objects appended one after another.
-	// We'll try to parse from the end. We'll do something simplistic:
-	blobs := make([]map[string]any, 0)
-	decoder := json.NewDecoder(stringToReader(string(data)))
-
-	for {
-		var m map[string]any
-		if err := decoder.Decode(&m); err != nil {
-			break // we assume we hit EOF or a decode error
-		}
-		blobs = append(blobs, m)
+		t.Fatalf("failed to reopen cache: %v", err)
 	}
+	t.Cleanup(func() { cache.Close() })
 
-	if len(blobs) == 0 {
-		return nil
-	}
-	return blobs[len(blobs)-1] // last blob
-}
-
-// stringToReader is a convenience to make a string into an io.Reader
-func stringToReader(s string) *stringReader {
-	return &stringReader{str: s}
-}
-
-type stringReader struct {
-	str string
-	pos int
-}
-
-func (sr *stringReader) Read(p []byte) (n int, err error) {
-	if sr.pos >= len(sr.str) {
-		return 0, fmt.Errorf("EOF")
-	}
-	n = copy(p, sr.str[sr.pos:])
-	sr.pos += n
-	return n, nil
+	return cache
 }
 
 // TestNewCache tests that we can properly create a new cache from a file.
@@ -81,6 +56,7 @@ func TestNewCache(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewCache failed: %v", err)
 	}
+	defer cache.Close()
 
 	// Confirm existing keys
 	ok, val := cache.Get("hello")
@@ -106,6 +82,29 @@ func TestNewCache(t *testing.T) {
 	}
 }
 
+// TestNewCacheMissingFile tests that a missing file is an empty cache, and
+// that the file is created on the first write.
+func TestNewCacheMissingFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "nested", "cache.json")
+
+	cache, err := NewCache[string, string](filePath)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected an empty cache, got %d entries", cache.Len())
+	}
+
+	cache.Set("hello", "world")
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if ok, val := reopen(t, filePath).Get("hello"); !ok || val != "world" {
+		t.Errorf("expected 'hello' = 'world' in the created file, got %v %q", ok, val)
+	}
+}
+
 // TestCacheSetAndGet tests that Set overwrites/creates a key and Get retrieves it.
 func TestCacheSetAndGet(t *testing.T) {
 	filePath := createTestFile(t, map[string]string{
@@ -116,13 +115,14 @@ func TestCacheSetAndGet(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewCache failed: %v", err)
 	}
+	defer cache.Close()
 
 	// Set a new key
 	cache.Set("hello", "world")
 
-	// Let the background file write complete (a small sleep is usually not ideal in production tests,
-	// but okay in a simple example).
-	time.Sleep(100 * time.Millisecond)
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
 
 	// Check in-memory value
 	ok, val := cache.Get("hello")
@@ -133,13 +133,50 @@ func TestCacheSetAndGet(t *testing.T) {
 		t.Errorf("expected 'world', got %s", val)
 	}
 
-	// Also check last blob in the file to ensure it’s persisted
-	lastBlob := parseLastJSONBlob(t, filePath)
-	if lastBlob == nil {
-		t.Fatalf("could not find any JSON objects in file")
+	// Also check the file to ensure it's persisted
+	persisted := reopen(t, filePath)
+	for key, want := range map[string]string{"initialKey": "initialValue", "hello": "world"} {
+		if ok, got := persisted.Get(key); !ok || got != want {
+			t.Errorf("expected file to have %q = %q, got %v %q", key, want, ok, got)
+		}
+	}
+
+	// The file is replaced by renaming, no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(filePath))
+	if err != nil {
+		t.Fatalf("failed to list cache directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the cache file, got %d files", len(entries))
+	}
+}
+
+// TestCacheBackgroundWrite tests that Sets are written without a Flush once
+// the flush interval has passed.
+func TestCacheBackgroundWrite(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "cache.json")
+
+	cache, err := NewCacheWithOptions[string, string](filePath, CacheOptions{FlushInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewCacheWithOptions failed: %v", err)
+	}
+	defer cache.Close()
+
+	cache.Set("hello", "world")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(filePath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cache was not written in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
 	}
-	if lastBlob["hello"] != "world" {
-		t.Errorf("expected file's last JSON blob to have 'hello' = 'world'; got %v", lastBlob["hello"])
+
+	if ok, val := reopen(t, filePath).Get("hello"); !ok || val != "world" {
+		t.Errorf("expected 'hello' = 'world' in the file, got %v %q", ok, val)
 	}
 }
 
@@ -150,7 +187,7 @@ func TestCacheConcurrency(t *testing.T) {
 		"concurrent": "test",
 	})
 
-	cache, err := NewCache[string, string](filePath)
+	cache, err := NewCacheWithOptions[string, string](filePath, CacheOptions{FlushInterval: time.Millisecond})
 	if err != nil {
 		t.Fatalf("NewCache failed: %v", err)
 	}
@@ -168,8 +205,9 @@ func TestCacheConcurrency(t *testing.T) {
 	}
 	wg.Wait()
 
-	// Wait a bit to let background file writes complete
-	time.Sleep(300 * time.Millisecond)
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
 
 	// Check in-memory results
 	for i := 0; i < numGoroutines; i++ {
@@ -185,22 +223,75 @@ func TestCacheConcurrency(t *testing.T) {
 		}
 	}
 
-	// Confirm that the last blob in the file also has them. (Depending on how many sets
-	// were appended, we'll just check the last one.)
-	lastBlob := parseLastJSONBlob(t, filePath)
-	if lastBlob == nil {
-		t.Fatalf("could not find any JSON objects in file after concurrency test")
-	}
-
+	// Confirm that the file also has them
+	persisted := reopen(t, filePath)
 	for i := 0; i < numGoroutines; i++ {
 		key := fmt.Sprintf("key_%d", i)
 		val := fmt.Sprintf("val_%d", i)
 
-		if got, ok := lastBlob[key]; !ok {
-			t.Errorf("file last JSON blob missing key %s", key)
+		if ok, got := persisted.Get(key); !ok {
+			t.Errorf("file missing key %s", key)
 		} else if got != val {
-			t.Errorf("expected file last JSON blob's %s = %s, got %v", key, val, got)
+			t.Errorf("expected file's %s = %s, got %v", key, val, got)
+		}
+	}
+}
+
+// TestCacheExpiry tests that expired entries are neither returned nor
+// written to the file.
+func TestCacheExpiry(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "cache.json")
+
+	cache, err := NewCacheWithOptions[string, string](filePath, CacheOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("NewCacheWithOptions failed: %v", err)
+	}
+
+	cache.Set("default", "kept")
+	cache.SetWithTTL("short", "expired", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if ok, _ := cache.Get("short"); ok {
+		t.Errorf("did not expect expired key 'short' to exist")
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	persisted := reopen(t, filePath)
+	if ok, val := persisted.Get("default"); !ok || val != "kept" {
+		t.Errorf("expected 'default' = 'kept' in the file, got %v %q", ok, val)
+	}
+	if persisted.Len() != 1 {
+		t.Errorf("expected 1 entry in the file, got %d", persisted.Len())
+	}
+}
+
+// TestCacheMaxEntries tests that the least recently used entries are evicted
+// beyond the size bound.
+func TestCacheMaxEntries(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "cache.json")
+
+	cache, err := NewCacheWithOptions[string, string](filePath, CacheOptions{MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("NewCacheWithOptions failed: %v", err)
+	}
+	defer cache.Close()
+
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Get("a")
+	cache.Set("c", "3")
+
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", cache.Len())
+	}
+	if ok, _ := cache.Get("b"); ok {
+		t.Errorf("expected least recently used key 'b' to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if ok, _ := cache.Get(key); !ok {
+			t.Errorf("expected key %q to be kept", key)
 		}
 	}
 }
-*/
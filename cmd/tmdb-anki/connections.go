@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/anki"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+type connectionsOptions struct {
+	deckOptions
+	limit       int
+	maxPerMovie int
+	concurrency int
+	prune       bool
+	dryRun      bool
+}
+
+func newConnectionsCommand(global *globalOptions) *cobra.Command {
+	opts := &connectionsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "connections",
+		Short: "Create and update Anki notes asking what links two movies",
+		Long: `Create and update one Anki note per pair of the cast's movies sharing
+people from the cast list, asking for the people linking them. Each pair
+gets one note, pairs of popular movies first.
+
+The notes use a "Movie Connection" note type with the fields Movies, First
+Movie, Second Movie and People, which has to exist in Anki. Requires Anki
+to be running with the AnkiConnect add-on, unless --dry-run only prints
+the pairs.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			if err := opts.apply(cmd, config); err != nil {
+				return err
+			}
+			return runConnections(config, opts)
+		},
+	}
+
+	opts.register(cmd)
+	cmd.Flags().IntVarP(&opts.limit, "limit", "n", 200, "maximum number of pairs, 0 makes a note for every pair")
+	cmd.Flags().IntVar(&opts.maxPerMovie, "max-per-movie", 5, "maximum number of pairs per movie, 0 doesn't limit")
+	cmd.Flags().IntVar(&opts.concurrency, "concurrency", 5, "number of notes to upsert in parallel")
+	cmd.Flags().BoolVar(&opts.prune, "prune", true, "remove connection notes for pairs that are no longer generated")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "print the pairs instead of writing notes")
+
+	return cmd
+}
+
+func runConnections(config *tmdbankigenerator.Config, opts *connectionsOptions) error {
+	if opts.limit < 0 {
+		return usageError{fmt.Errorf("--limit must not be negative, got %d", opts.limit)}
+	}
+	if opts.maxPerMovie < 0 {
+		return usageError{fmt.Errorf("--max-per-movie must not be negative, got %d", opts.maxPerMovie)}
+	}
+	if opts.concurrency <= 0 {
+		return usageError{fmt.Errorf("--concurrency must be positive, got %d", opts.concurrency)}
+	}
+
+	movies, err := castMovies(config)
+	if err != nil {
+		return err
+	}
+
+	connections := tmdbankigenerator.MovieConnections(movies, tmdbankigenerator.ConnectionOptions{
+		Limit:       opts.limit,
+		MaxPerMovie: opts.maxPerMovie,
+	})
+
+	if opts.dryRun {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FIRST\tSECOND\tPEOPLE")
+		for _, connection := range connections {
+			note := connectionNote(connection)
+			fmt.Fprintf(w, "%s\t%s\t%s\n", note.First, note.Second, strings.Join(note.People, ", "))
+		}
+		return w.Flush()
+	}
+
+	client, err := anki.NewAnkiClient(config.Deck)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to ankiconnect")
+	}
+
+	g := errgroup.Group{}
+	g.SetLimit(opts.concurrency)
+	mu := sync.Mutex{}
+
+	notesToKeep := make([]int64, 0, len(connections))
+
+	for _, connection := range connections {
+		note := connectionNote(connection)
+
+		g.Go(func() error {
+			id, err := client.UpsertConnectionNote(&note)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			notesToKeep = append(notesToKeep, id)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return errors.Wrap(err, "failed to upsert notes")
+	}
+
+	if opts.prune {
+		return client.RemoveUnusedConnectionIDs(notesToKeep)
+	}
+
+	return nil
+}
+
+func connectionNote(connection tmdbankigenerator.MovieConnection) anki.ConnectionNote {
+	note := anki.ConnectionNote{
+		First:  anki.ConnectionMovie{TMDbID: connection.First.ID, Title: connection.First.Title, ReleaseDate: connection.First.ReleaseDate},
+		Second: anki.ConnectionMovie{TMDbID: connection.Second.ID, Title: connection.Second.Title, ReleaseDate: connection.Second.ReleaseDate},
+	}
+	for _, person := range connection.People {
+		note.People = append(note.People, person.Name)
+	}
+	return note
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/JonasRothmann/ankiconnect"
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/anki"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type diffOptions struct {
+	deckOptions
+	minPopularityChange float32
+	limit               int
+	notes               bool
+}
+
+func newDiffCommand(global *globalOptions) *cobra.Command {
+	opts := &diffOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "diff <old.db> [new.db]",
+		Short: "Compare two databases and print what changed",
+		Long: `Compare two databases, or a snapshot against the database from the
+config, and print the movies, people and credits that were added or
+removed, the movies that were renamed and the big popularity moves.
+
+new.db defaults to the database from the config. Take snapshots to compare
+against with the snapshot command. Both databases are only read, migrate
+older ones first.
+
+With --notes it also prints the Anki notes of the cast's movies that the
+change would create, remove or update on the next sync.`,
+		Args: usageArgs(cobra.RangeArgs(1, 2)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			if err := opts.apply(cmd, config); err != nil {
+				return err
+			}
+			newPath := config.Database.Path
+			if len(args) == 2 {
+				newPath = args[1]
+			}
+			return runDiff(config, args[0], newPath, opts)
+		},
+	}
+
+	opts.register(cmd)
+	cmd.Flags().Float32Var(&opts.minPopularityChange, "min-popularity-change", 10, "only print popularity moves at least this big")
+	cmd.Flags().IntVarP(&opts.limit, "limit", "n", 20, "maximum number of rows to print per section, 0 prints all")
+	cmd.Flags().BoolVar(&opts.notes, "notes", false, "also print the Anki notes the change would affect")
+
+	return cmd
+}
+
+func newSnapshotCommand(global *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "snapshot <path>",
+		Short: "Save a copy of the database to diff against later",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runSnapshot(config, args[0])
+		},
+	}
+}
+
+func runSnapshot(config *tmdbankigenerator.Config, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return usageError{fmt.Errorf("%s already exists", path)}
+	}
+
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	if err := db.Snapshot(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved snapshot of %s to %s\n", config.Database.Path, path)
+	return nil
+}
+
+func runDiff(config *tmdbankigenerator.Config, oldPath, newPath string, opts *diffOptions) error {
+	if opts.limit < 0 {
+		return usageError{fmt.Errorf("--limit must not be negative, got %d", opts.limit)}
+	}
+	if opts.minPopularityChange < 0 {
+		return usageError{fmt.Errorf("--min-popularity-change must not be negative, got %g", opts.minPopularityChange)}
+	}
+
+	open := func(path string) (*tmdbankigenerator.Database, error) {
+		opts := config.Database.Options(true)
+		opts.Path = path
+		db, err := tmdbankigenerator.NewDatabase(opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to start database %s", path)
+		}
+		return db, nil
+	}
+
+	oldDB, err := open(oldPath)
+	if err != nil {
+		return err
+	}
+	defer oldDB.Close()
+
+	newDB, err := open(newPath)
+	if err != nil {
+		return err
+	}
+	defer newDB.Close()
+
+	diff, err := tmdbankigenerator.DiffDatabases(oldDB, newDB, tmdbankigenerator.DiffOptions{
+		MinPopularityChange: opts.minPopularityChange,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to diff databases")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	printDatasetDiff(w, diff, opts.limit)
+
+	if opts.notes {
+		changes, err := noteChanges(config, oldDB, newDB)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "NOTE\tTMDB ID\tTITLE\tCHANGED")
+		printRows(w, changes, opts.limit, func(change noteChange) {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", change.status, change.note.TMDbID, change.note.MovieTitle, strings.Join(change.fields, ", "))
+		})
+		if len(changes) == 0 {
+			fmt.Fprintln(w, "no notes affected")
+		}
+	}
+
+	return w.Flush()
+}
+
+func printDatasetDiff(w io.Writer, diff *tmdbankigenerator.DatasetDiff, limit int) {
+	fmt.Fprintf(w, "Movies:\t+%d -%d, %d renamed\n", len(diff.AddedMovies), len(diff.RemovedMovies), len(diff.RenamedMovies))
+	fmt.Fprintf(w, "People:\t+%d -%d\n", len(diff.AddedPeople), len(diff.RemovedPeople))
+	fmt.Fprintf(w, "Credits:\t+%d -%d\n", len(diff.AddedCredits), len(diff.RemovedCredits))
+	fmt.Fprintf(w, "Popularity moves:\t%d\n", len(diff.PopularityChanges))
+
+	for _, section := range []struct {
+		name   string
+		movies []tmdbankigenerator.Movie
+	}{{"ADDED MOVIE", diff.AddedMovies}, {"REMOVED MOVIE", diff.RemovedMovies}} {
+		if len(section.movies) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\tID\tPOPULARITY\n", section.name)
+		printRows(w, section.movies, limit, func(movie tmdbankigenerator.Movie) {
+			fmt.Fprintf(w, "%s\t%d\t%.1f\n", movie.Title, movie.ID, movie.Popularity)
+		})
+	}
+
+	if len(diff.RenamedMovies) > 0 {
+		fmt.Fprintln(w, "\nRENAMED MOVIE\tID\tOLD TITLE")
+		printRows(w, diff.RenamedMovies, limit, func(rename tmdbankigenerator.MovieRename) {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", rename.NewTitle, rename.ID, rename.OldTitle)
+		})
+	}
+
+	for _, section := range []struct {
+		name   string
+		people []tmdbankigenerator.Person
+	}{{"ADDED PERSON", diff.AddedPeople}, {"REMOVED PERSON", diff.RemovedPeople}} {
+		if len(section.people) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\tID\tPOPULARITY\n", section.name)
+		printRows(w, section.people, limit, func(person tmdbankigenerator.Person) {
+			fmt.Fprintf(w, "%s\t%d\t%.1f\n", person.Name, person.ID, person.Popularity)
+		})
+	}
+
+	for _, section := range []struct {
+		name    string
+		credits []tmdbankigenerator.CreditChange
+	}{{"ADDED CREDIT", diff.AddedCredits}, {"REMOVED CREDIT", diff.RemovedCredits}} {
+		if len(section.credits) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\tPERSON\tJOB\n", section.name)
+		printRows(w, section.credits, limit, func(credit tmdbankigenerator.CreditChange) {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", credit.MovieTitle, credit.PersonName, credit.JobType)
+		})
+	}
+
+	if len(diff.PopularityChanges) > 0 {
+		fmt.Fprintln(w, "\nPOPULARITY MOVE\tKIND\tOLD\tNEW\tCHANGE")
+		printRows(w, diff.PopularityChanges, limit, func(change tmdbankigenerator.PopularityChange) {
+			fmt.Fprintf(w, "%s\t%s\t%.1f\t%.1f\t%+.1f\n", change.Name, change.Kind, change.Old, change.New, change.Delta())
+		})
+	}
+}
+
+// printRows prints the first limit rows, and how many were left out.
+func printRows[T any](w io.Writer, rows []T, limit int, print func(T)) {
+	for i, row := range rows {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(w, "... and %d more\n", len(rows)-limit)
+			return
+		}
+		print(row)
+	}
+}
+
+type noteChange struct {
+	status string
+	note   anki.MovieNote
+	fields []string
+}
+
+// noteChanges builds the notes of the cast's movies from both databases and
+// compares them. The cast is resolved against the new database.
+func noteChanges(config *tmdbankigenerator.Config, oldDB, newDB *tmdbankigenerator.Database) ([]noteChange, error) {
+	ids, extraIds, err := tmdbankigenerator.GetCastIDs(config, newDB)
+	if err != nil {
+		return nil, err
+	}
+
+	oldMovies, err := castMoviesFrom(config, oldDB, ids, extraIds)
+	if err != nil {
+		return nil, err
+	}
+	newMovies, err := castMoviesFrom(config, newDB, ids, extraIds)
+	if err != nil {
+		return nil, err
+	}
+
+	oldNotes := make(map[int]anki.MovieNote, len(oldMovies))
+	for _, movie := range oldMovies {
+		oldNotes[movie.ID] = movieNote(movie, config.ShowCharacters)
+	}
+
+	changes := []noteChange{}
+	seen := make(map[int]bool, len(newMovies))
+	for _, movie := range newMovies {
+		note := movieNote(movie, config.ShowCharacters)
+		seen[movie.ID] = true
+
+		oldNote, ok := oldNotes[movie.ID]
+		if !ok {
+			changes = append(changes, noteChange{status: "new", note: note})
+			continue
+		}
+		if fields := changedNoteFields(oldNote, note); len(fields) > 0 {
+			changes = append(changes, noteChange{status: "updated", note: note, fields: fields})
+		}
+	}
+	for _, movie := range oldMovies {
+		if !seen[movie.ID] {
+			changes = append(changes, noteChange{status: "removed", note: oldNotes[movie.ID]})
+		}
+	}
+
+	return changes, nil
+}
+
+// changedNoteFields lists the fields that differ between two notes built from
+// the database, compared the way they're written to Anki.
+func changedNoteFields(old, new anki.MovieNote) []string {
+	var fields []string
+	if old.MovieTitle != new.MovieTitle {
+		fields = append(fields, "title")
+	}
+	if old.ReleaseDate.Format("2006") != new.ReleaseDate.Format("2006") {
+		fields = append(fields, "year")
+	}
+	if strconv.FormatFloat(float64(old.Popularity), 'g', 2, 64) != strconv.FormatFloat(float64(new.Popularity), 'g', 2, 64) {
+		fields = append(fields, "popularity")
+	}
+	if !slices.Equal(old.Genres, new.Genres) {
+		fields = append(fields, "genres")
+	}
+	if old.PeopleField() != new.PeopleField() {
+		fields = append(fields, "people")
+	}
+	if !slices.EqualFunc(old.Pictures, new.Pictures, func(a, b ankiconnect.Picture) bool { return a.Filename == b.Filename }) {
+		fields = append(fields, "pictures")
+	}
+	return fields
+}
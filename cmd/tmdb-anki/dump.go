@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type dumpOptions struct {
+	gzip bool
+}
+
+func newDumpCommand(global *globalOptions) *cobra.Command {
+	opts := &dumpOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "dump <dir>",
+		Short: "Write the database to NDJSON files, to review and share in git",
+		Long: `Write every table of the database to <dir>, one NDJSON file per table
+with one row per line, sorted by primary key, next to a manifest.json with
+the schema version and row counts. The same data always dumps to the same
+files, so changes to the dataset can be reviewed with git diff. Rebuild a
+database from a dump with the load command.`,
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runDump(config, args[0], opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.gzip, "gzip", false, "gzip the table files")
+
+	return cmd
+}
+
+func runDump(config *tmdbankigenerator.Config, dir string, opts *dumpOptions) error {
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	manifest, err := db.Dump(dir, tmdbankigenerator.DumpOptions{Gzip: opts.gzip})
+	if err != nil {
+		return errors.Wrap(err, "failed to dump database")
+	}
+
+	rows := 0
+	for _, count := range manifest.Tables {
+		rows += count
+	}
+	fmt.Printf("Dumped %d rows of %s to %s\n", rows, config.Database.Path, dir)
+
+	return nil
+}
+
+type loadOptions struct {
+	force bool
+}
+
+func newLoadCommand(global *globalOptions) *cobra.Command {
+	opts := &loadOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "load <dir>",
+		Short: "Rebuild the database from a dump",
+		Long: `Rebuild the database from the NDJSON files the dump command wrote to
+<dir>, plain or gzipped. Refuses to replace an existing database without
+--force. The database is only replaced once the whole dump loaded.`,
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runLoad(config, args[0], opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.force, "force", false, "replace an existing database")
+
+	return cmd
+}
+
+func runLoad(config *tmdbankigenerator.Config, dir string, opts *loadOptions) error {
+	path := config.Database.Path
+	if _, err := os.Stat(path); err == nil && !opts.force {
+		return usageError{fmt.Errorf("%s already exists, use --force to replace it", path)}
+	}
+
+	if _, err := tmdbankigenerator.ReadDumpManifest(dir); err != nil {
+		return usageError{err}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".load-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	// Without a write-ahead log, so the loaded database is all in the one
+	// file that's moved. The journal mode is set again when it's opened.
+	dbOptions := config.Database.Options(false)
+	dbOptions.Path = tmp.Name()
+	dbOptions.WAL = false
+	db, err := tmdbankigenerator.NewDatabase(dbOptions)
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+
+	manifest, err := db.Load(dir)
+	db.Close()
+	if err != nil {
+		return errors.Wrapf(err, "failed to load %s", dir)
+	}
+
+	if err := tmdbankigenerator.ReplaceDatabase(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	rows := 0
+	for _, count := range manifest.Tables {
+		rows += count
+	}
+	fmt.Printf("Loaded %d rows from %s into %s\n", rows, dir, path)
+
+	return nil
+}
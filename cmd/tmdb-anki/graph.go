@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type graphOptions struct {
+	jobs               []string
+	minMoviePopularity float32
+	adult              bool
+}
+
+func newGraphCommand(global *globalOptions) *cobra.Command {
+	opts := &graphOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Chain movies through the people they share, like Cine2Nerdle",
+		Long: `Chain movies through the people they share, the way Cine2Nerdle links
+them. Movies are given by TMDB ID or title, titles are searched like the
+search command and the best match is used.
+
+Every credit links movies unless --job restricts the job types, and adult
+movies are left out unless --adult is set.`,
+	}
+
+	opts.register(cmd, true, 0)
+	cmd.AddCommand(
+		newGraphChainCommand(global, opts),
+		newGraphNeighborsCommand(global, opts),
+		newGraphConnectCommand(global, opts),
+	)
+
+	return cmd
+}
+
+func newGraphChainCommand(global *globalOptions, opts *graphOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "chain <movie> <movie>",
+		Short: "Print the shortest chain of shared people from one movie to another",
+		Args:  usageArgs(cobra.ExactArgs(2)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			g, movieIDs, err := opts.load(config, args)
+			if err != nil {
+				return err
+			}
+
+			chain, ok := g.ShortestChain(movieIDs[0], movieIDs[1])
+			if !ok {
+				return fmt.Errorf("no chain links %s and %s", movieLabel(g, movieIDs[0]), movieLabel(g, movieIDs[1]))
+			}
+
+			for i, movieID := range chain.MovieIDs {
+				fmt.Println(movieLabel(g, movieID))
+				if i < len(chain.PersonIDs) {
+					fmt.Printf("  via %s\n", personLabel(g, chain.PersonIDs[i], movieID, chain.MovieIDs[i+1]))
+				}
+			}
+			if chain.Links() == 1 {
+				fmt.Println("1 link")
+			} else {
+				fmt.Printf("%d links\n", chain.Links())
+			}
+			return nil
+		},
+	}
+}
+
+func newGraphNeighborsCommand(global *globalOptions, opts *graphOptions) *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "neighbors <movie>",
+		Short: "Print the movies one link away from a movie",
+		Long: `Print the movies sharing a person with a movie, the ones sharing the
+most people first, then the most popular.`,
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit < 0 {
+				return usageError{fmt.Errorf("--limit must not be negative, got %d", limit)}
+			}
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			g, movieIDs, err := opts.load(config, args)
+			if err != nil {
+				return err
+			}
+
+			neighbors := g.Neighbors(movieIDs[0])
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "MOVIE\tID\tPOPULARITY\tVIA")
+			printRows(w, neighbors, limit, func(neighbor graph.Neighbor) {
+				movie, _ := g.Movie(neighbor.MovieID)
+				people := make([]string, len(neighbor.PersonIDs))
+				for i, personID := range neighbor.PersonIDs {
+					people[i] = personLabel(g, personID, movieIDs[0], neighbor.MovieID)
+				}
+				fmt.Fprintf(w, "%s\t%d\t%.1f\t%s\n", movieLabel(g, movie.ID), movie.ID, movie.Popularity, strings.Join(people, ", "))
+			})
+			if len(neighbors) == 0 {
+				fmt.Fprintln(w, "no movies share a person")
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "maximum number of movies to print, 0 prints all")
+
+	return cmd
+}
+
+func newGraphConnectCommand(global *globalOptions, opts *graphOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "connect <movie> <movie>",
+		Short: "Print the people credited on both movies",
+		Args:  usageArgs(cobra.ExactArgs(2)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			g, movieIDs, err := opts.load(config, args)
+			if err != nil {
+				return err
+			}
+
+			people := g.Connectors(movieIDs[0], movieIDs[1])
+			if len(people) == 0 {
+				fmt.Printf("Nobody links %s and %s\n", movieLabel(g, movieIDs[0]), movieLabel(g, movieIDs[1]))
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PERSON\tID\tPOPULARITY\tFIRST\tSECOND")
+			for _, personID := range people {
+				person, _ := g.Person(personID)
+				fmt.Fprintf(w, "%s\t%d\t%.1f\t%s\t%s\n", person.Name, person.ID, person.Popularity,
+					jobTypesLabel(g.JobTypes(personID, movieIDs[0])), jobTypesLabel(g.JobTypes(personID, movieIDs[1])))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// register adds the flags to cmd, to its subcommands too when persistent.
+func (o *graphOptions) register(cmd *cobra.Command, persistent bool, minMoviePopularity float32) {
+	flags := cmd.Flags()
+	if persistent {
+		flags = cmd.PersistentFlags()
+	}
+
+	jobTypes := make([]string, 0)
+	for _, jobType := range tmdbankigenerator.JobTypes() {
+		jobTypes = append(jobTypes, string(jobType))
+	}
+	flags.StringSliceVar(&o.jobs, "job", nil, "only link movies through credits of these job types: "+strings.Join(jobTypes, ", "))
+	flags.Float32Var(&o.minMoviePopularity, "min-movie-popularity", minMoviePopularity, "leave out movies below this TMDB popularity")
+	flags.BoolVar(&o.adult, "adult", false, "include adult movies")
+}
+
+// query returns the links the flags select.
+func (o *graphOptions) query() (tmdbankigenerator.LinkQuery, error) {
+	if o.minMoviePopularity < 0 {
+		return tmdbankigenerator.LinkQuery{}, usageError{fmt.Errorf("--min-movie-popularity must not be negative, got %g", o.minMoviePopularity)}
+	}
+
+	query := tmdbankigenerator.LinkQuery{
+		MinMoviePopularity: o.minMoviePopularity,
+		IncludeAdult:       o.adult,
+	}
+	for _, name := range o.jobs {
+		jobType, ok := tmdbankigenerator.ParseJobType(name)
+		if !ok {
+			return tmdbankigenerator.LinkQuery{}, usageError{fmt.Errorf("unknown job type %q", name)}
+		}
+		query.JobTypes = append(query.JobTypes, jobType)
+	}
+	return query, nil
+}
+
+// load builds the graph from the database and looks up the movies given as
+// arguments in it.
+func (o *graphOptions) load(config *tmdbankigenerator.Config, args []string) (*graph.Graph, []int, error) {
+	query, err := o.query()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	movieIDs := make([]int, len(args))
+	for i, arg := range args {
+		if movieIDs[i], err = findMovie(db, arg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	g, err := graph.Load(db, query)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load graph")
+	}
+
+	for i, movieID := range movieIDs {
+		if _, ok := g.Movie(movieID); !ok {
+			return nil, nil, usageError{fmt.Errorf("movie %q isn't in the graph, check --min-movie-popularity and --adult", args[i])}
+		}
+	}
+
+	return g, movieIDs, nil
+}
+
+// findMovie returns the ID of a movie given by TMDB ID or title.
+func findMovie(db *tmdbankigenerator.Database, arg string) (int, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		return id, nil
+	}
+
+	results, err := db.SearchMovies(arg, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, usageError{fmt.Errorf("no movie matches %q", arg)}
+	}
+	return results[0].ID, nil
+}
+
+func movieLabel(g *graph.Graph, movieID int) string {
+	movie, ok := g.Movie(movieID)
+	if !ok {
+		return strconv.Itoa(movieID)
+	}
+	if movie.ReleaseDate.IsZero() {
+		return movie.Title
+	}
+	return fmt.Sprintf("%s (%d)", movie.Title, movie.ReleaseDate.Year())
+}
+
+// personLabel names a person with what they did on the two movies they link.
+func personLabel(g *graph.Graph, personID, a, b int) string {
+	person, _ := g.Person(personID)
+	from, to := jobTypesLabel(g.JobTypes(personID, a)), jobTypesLabel(g.JobTypes(personID, b))
+	if from == to {
+		return fmt.Sprintf("%s (%s)", person.Name, from)
+	}
+	return fmt.Sprintf("%s (%s, %s)", person.Name, from, to)
+}
+
+func jobTypesLabel(jobTypes []tmdbankigenerator.JobType) string {
+	names := make([]string, len(jobTypes))
+	for i, jobType := range jobTypes {
+		names[i] = string(jobType)
+	}
+	return strings.Join(names, "/")
+}
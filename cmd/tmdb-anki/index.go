@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type indexOptions struct {
+	tmdbankigenerator.IndexOptions
+	max           int
+	reset         bool
+	noCache       bool
+	requestBudget int
+}
+
+func newIndexCommand(global *globalOptions) *cobra.Command {
+	opts := &indexOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "Crawl the most popular movies from TMDB into the database",
+		Long: `Crawl the most popular movies of the movie export from TMDB and store
+their people and credits in the database.
+
+Progress is written to the database in batches. An interrupted crawl,
+whether by an error or Ctrl-C, continues where it left off on the next
+run. Use --reset to crawl every movie again. Requests that are rate
+limited or fail on TMDB's side are retried with backoff, and
+--request-budget stops the crawl after that many requests.
+
+TMDB responses are cached in the file set by [cache] in the config, so
+indexing again with other filters doesn't fetch them again. Use
+--no-cache to bypass the cache.
+
+Requires TMDB_API_KEY to be set in the environment or in a .env file.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runIndex(cmd.Context(), config, opts)
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.max, "max", 10000, "number of most popular movies to crawl")
+	cmd.Flags().Float32Var(&opts.MinPopularity, "min-popularity", 1, "skip movies and people below this TMDB popularity")
+	cmd.Flags().IntVar(&opts.MinVoteCount, "min-vote-count", 10, "skip movies with fewer TMDB votes than this")
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", 100, "number of crawled movies written to the database at a time")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", 30, "number of movies fetched from TMDB in parallel")
+	cmd.Flags().BoolVar(&opts.reset, "reset", false, "forget which movies were crawled before and start over")
+	cmd.Flags().BoolVar(&opts.noCache, "no-cache", false, "fetch everything from TMDB instead of the response cache")
+	cmd.Flags().IntVar(&opts.requestBudget, "request-budget", 0, "stop after sending this many requests to TMDB, 0 is unlimited")
+
+	return cmd
+}
+
+func runIndex(ctx context.Context, config *tmdbankigenerator.Config, opts *indexOptions) error {
+	for flag, value := range map[string]int{"--max": opts.max, "--batch-size": opts.BatchSize, "--concurrency": opts.Concurrency} {
+		if value <= 0 {
+			return usageError{fmt.Errorf("%s must be positive, got %d", flag, value)}
+		}
+	}
+	if opts.requestBudget < 0 {
+		return usageError{fmt.Errorf("--request-budget must not be negative, got %d", opts.requestBudget)}
+	}
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "failed to load .env file")
+	}
+
+	tmdbApiKey, ok := os.LookupEnv("TMDB_API_KEY")
+	if !ok {
+		return &tmdbankigenerator.ConfigError{File: ".env", Key: "TMDB_API_KEY", Msg: "no tmdb api key set"}
+	}
+
+	database, err := tmdbankigenerator.NewDatabase(config.Database.Options(false))
+	if err != nil {
+		return errors.Wrap(err, "failed to start database")
+	}
+	defer database.Close()
+
+	database.SetReferentialIntegrity(false)
+	defer database.SetReferentialIntegrity(true)
+
+	if opts.reset {
+		if err := database.ResetCrawlState(); err != nil {
+			return err
+		}
+	}
+
+	tmdb, err := tmdbankigenerator.NewTMDbClient(tmdbApiKey, tmdbankigenerator.DefaultTMDbBaseURL)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to tmdb")
+	}
+	tmdb.SetRequestBudget(opts.requestBudget)
+
+	if !opts.noCache {
+		cache, err := tmdbankigenerator.OpenResponseCache(config.Cache.Path, time.Duration(config.Cache.TTLDays)*24*time.Hour, config.Cache.MaxEntries)
+		if err != nil {
+			return errors.Wrap(err, "failed to open response cache")
+		}
+		defer func() {
+			if err := cache.Close(); err != nil {
+				log.Printf("failed to save response cache: %s", err)
+			}
+		}()
+		tmdb.SetCache(cache)
+	}
+
+	export, err := config.Exports.Find(tmdbankigenerator.MovieExport)
+	if err != nil {
+		return err
+	}
+	log.Printf("using %s", export.Path)
+
+	popularMovies, report, err := tmdbankigenerator.GetTopMovies(export.Path, opts.MinPopularity, opts.max)
+	if err != nil {
+		return errors.Wrap(err, "failed to get top movies")
+	}
+	report.LogMalformed()
+
+	// Recorded before crawling, an interrupted crawl is from these exports
+	// too. Cast names are resolved against the person export, which indexing
+	// doesn't need.
+	if err := database.SetExportMetadata(export); err != nil {
+		return err
+	}
+	if personExport, err := config.Exports.Find(tmdbankigenerator.PersonExport); err != nil {
+		log.Printf("Warning: not recording the person export: %s", err)
+	} else if err := database.SetExportMetadata(personExport); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Print("starting")
+	stats, err := tmdbankigenerator.NewIndexer(database, tmdb, opts.IndexOptions).Index(ctx, popularMovies)
+	fmt.Printf("Got %d people, %d movies and %d credits, skipped %d movies, sent %d requests\n", stats.People, stats.Indexed, stats.Credits, stats.Skipped, tmdb.Requests())
+	if errors.Is(err, tmdbankigenerator.ErrRequestBudgetExhausted) {
+		return errors.Wrap(err, "run index again to continue")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("done")
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	exitOK     = 0
+	exitError  = 1
+	exitUsage  = 2
+	exitConfig = 3
+)
+
+// usageError marks errors caused by how the command was invoked, as opposed
+// to errors that happened while running it.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+type globalOptions struct {
+	configPath string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	opts := &globalOptions{}
+
+	root := &cobra.Command{
+		Use:   "tmdb-anki",
+		Short: "Generate Cine2Nerdle Anki cards from TMDB data",
+		Long: `tmdb-anki indexes movies, people and credits from TMDB into a local
+sqlite database and turns the movies of the people in config.toml into
+Anki notes through AnkiConnect.
+
+Exit codes:
+  0  success
+  1  the command failed
+  2  invalid arguments or flags
+  3  invalid configuration, including cast names that can't be resolved`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVarP(&opts.configPath, "config", "c", tmdbankigenerator.DefaultConfigPath, "path to the config file")
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError{err}
+	})
+
+	root.AddCommand(
+		newIndexCommand(opts),
+		newSyncCommand(opts),
+		newConnectionsCommand(opts),
+		newResolveCommand(opts),
+		newSearchCommand(opts),
+		newGraphCommand(opts),
+		newPlayCommand(opts),
+		newSuggestCommand(opts),
+		newDiffCommand(opts),
+		newSnapshotCommand(opts),
+		newDumpCommand(opts),
+		newLoadCommand(opts),
+		newStatsCommand(opts),
+		newPruneCommand(opts),
+		newMigrateCommand(opts),
+	)
+
+	root.SetArgs(args)
+	cmd, err := root.ExecuteC()
+	if err == nil {
+		return exitOK
+	}
+
+	fmt.Fprintf(os.Stderr, "tmdb-anki: %s\n", err)
+
+	var configErr *tmdbankigenerator.ConfigError
+	var configErrs tmdbankigenerator.ConfigErrors
+	var resolveErrs tmdbankigenerator.ResolveErrors
+	var usageErr usageError
+	switch {
+	case errors.As(err, &configErr), errors.As(err, &configErrs), errors.As(err, &resolveErrs):
+		return exitConfig
+	case errors.As(err, &usageErr), isUnknownCommand(err):
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+		return exitUsage
+	default:
+		return exitError
+	}
+}
+
+// isUnknownCommand reports whether cobra rejected the arguments before
+// running anything. Cobra doesn't export typed errors for these.
+func isUnknownCommand(err error) bool {
+	return strings.HasPrefix(err.Error(), "unknown command ")
+}
+
+func (o *globalOptions) loadConfig() (*tmdbankigenerator.Config, error) {
+	return tmdbankigenerator.LoadConfig(o.configPath)
+}
+
+// usageArgs wraps one of cobra's argument validators so that bad positional
+// arguments exit with exitUsage like bad flags do.
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return usageError{err}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type migrateOptions struct {
+	status bool
+	dryRun bool
+}
+
+func newMigrateCommand(global *globalOptions) *cobra.Command {
+	opts := &migrateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Bring the schema of the database up to date",
+		Long: `Apply the schema migrations the database is missing. Every command
+that opens the database does this too; migrate lets you see what will
+change first with --status or --dry-run.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runMigrate(config, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.status, "status", false, "print which migrations are applied and which are pending")
+	cmd.Flags().BoolVarP(&opts.dryRun, "dry-run", "n", false, "only print the migrations that would be applied")
+
+	return cmd
+}
+
+func runMigrate(config *tmdbankigenerator.Config, opts *migrateOptions) error {
+	db, err := tmdbankigenerator.OpenDatabase(config.Database.Options(opts.status || opts.dryRun))
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if opts.status {
+		fmt.Printf("%s is at schema version %d of %d\n\n", config.Database.Path, version, tmdbankigenerator.LatestSchemaVersion())
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+		for _, migration := range tmdbankigenerator.Migrations() {
+			status := "pending"
+			if migration.Version <= version {
+				status = "applied"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, status)
+		}
+		return w.Flush()
+	}
+
+	if opts.dryRun {
+		pending, err := db.PendingMigrations()
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			fmt.Printf("would apply %d: %s\n", migration.Version, migration.Name)
+		}
+		fmt.Printf("%s is at schema version %d, %d migrations pending\n", config.Database.Path, version, len(pending))
+		return nil
+	}
+
+	applied, err := db.Migrate()
+	for _, migration := range applied {
+		fmt.Printf("applied %d: %s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err = db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s is at schema version %d\n", config.Database.Path, version)
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/game"
+	"github.com/JonasRothmann/cine2nerdle-trainer/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// startMovies is how many of the most popular movies a game without a
+// starting movie picks one from.
+const startMovies = 100
+
+type playOptions struct {
+	graphOptions
+	bot string
+}
+
+func newPlayCommand(global *globalOptions) *cobra.Command {
+	opts := &playOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "play [movie]",
+		Short: "Practice Cine2Nerdle in the terminal",
+		Long: fmt.Sprintf(`Practice Cine2Nerdle in the terminal, offline against the database. Type
+the title of a movie sharing a person with the current one to chain it,
+with the release year in parentheses to pick between movies of the same
+title, e.g. "Dune (1984)". No movie can be played twice and each person can
+link movies %d times. Type "quit" to stop.
+
+The game starts from the given movie, by TMDB ID or title, or from one of
+the %d most popular movies. With --bot you take turns with the computer,
+which explains each of its moves, and whoever can't follow the last movie
+loses. An easy bot plays random movies, a medium one the movies leaving
+you the fewest options and a hard one also avoids movies you can answer
+with one it can't follow.`, game.MaxUses, startMovies),
+		Args: usageArgs(cobra.MaximumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runPlay(config, args, opts)
+		},
+	}
+
+	opts.register(cmd, false, 10)
+	cmd.Flags().StringVar(&opts.bot, "bot", "", "play against the computer: easy, medium or hard")
+
+	return cmd
+}
+
+func runPlay(config *tmdbankigenerator.Config, args []string, opts *playOptions) error {
+	query, err := opts.query()
+	if err != nil {
+		return err
+	}
+
+	var bot *game.Bot
+	if opts.bot != "" {
+		difficulty, ok := game.ParseDifficulty(opts.bot)
+		if !ok {
+			return usageError{fmt.Errorf("unknown difficulty %q, use easy, medium or hard", opts.bot)}
+		}
+		bot = game.NewBot(difficulty, rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	g, err := graph.Load(db, query)
+	if err != nil {
+		return errors.Wrap(err, "failed to load graph")
+	}
+
+	var startID int
+	if len(args) == 1 {
+		if startID, err = findMovie(db, args[0]); err != nil {
+			return err
+		}
+		if _, ok := g.Movie(startID); !ok {
+			return usageError{fmt.Errorf("movie %q isn't in the graph, check --min-movie-popularity and --adult", args[0])}
+		}
+	} else {
+		movieIDs := g.MovieIDs()
+		if len(movieIDs) == 0 {
+			return fmt.Errorf("no movies to play, check --min-movie-popularity and --job")
+		}
+		slices.SortFunc(movieIDs, func(a, b int) int {
+			movieA, _ := g.Movie(a)
+			movieB, _ := g.Movie(b)
+			return cmp.Compare(movieB.Popularity, movieA.Popularity)
+		})
+		startID = movieIDs[rand.IntN(min(len(movieIDs), startMovies))]
+	}
+
+	round, err := game.New(g, startID)
+	if err != nil {
+		return err
+	}
+	matcher := game.NewMatcher(db, g)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for !round.Over() {
+		fmt.Printf("\n%s\n> ", movieLabel(g, round.Current()))
+		if !scanner.Scan() {
+			break
+		}
+
+		input := strings.TrimSpace(scanner.Text())
+		switch strings.ToLower(input) {
+		case "":
+			continue
+		case "quit", "q":
+			printGameOver(round, bot != nil)
+			return nil
+		}
+
+		movieID, ok, err := matcher.Resolve(round, input)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Printf("No movie matches %q\n", input)
+			continue
+		}
+
+		previousID := round.Current()
+		move, err := round.Play(movieID)
+		if err != nil {
+			fmt.Printf("Can't play %s: %v\n", movieLabel(g, movieID), err)
+			continue
+		}
+		fmt.Printf("Linked through %s\n", linksLabel(round, previousID, move))
+
+		if bot == nil {
+			continue
+		}
+		choice, ok := bot.Choose(round)
+		if !ok {
+			fmt.Printf("\nNo movie can follow %s, you win!\n", movieLabel(g, round.Current()))
+			return nil
+		}
+		previousID = round.Current()
+		if _, err := round.Play(choice.Move.MovieID); err != nil {
+			return err
+		}
+		fmt.Printf("\nBot plays %s through %s\n  It %s\n", movieLabel(g, choice.Move.MovieID), linksLabel(round, previousID, choice.Move), choice.Reason)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	printGameOver(round, bot != nil)
+	return nil
+}
+
+// linksLabel names the people of a move with their uses so far.
+func linksLabel(round *game.Game, previousID int, move game.Move) string {
+	links := make([]string, len(move.PersonIDs))
+	for i, personID := range move.PersonIDs {
+		links[i] = fmt.Sprintf("%s %d/%d", personLabel(round.Graph(), personID, previousID, move.MovieID), round.Uses(personID), game.MaxUses)
+	}
+	return strings.Join(links, ", ")
+}
+
+// printGameOver ends a game on the player's turn.
+func printGameOver(round *game.Game, bot bool) {
+	g := round.Graph()
+
+	fmt.Printf("\nChained %d movies\n", len(round.Moves()))
+	moves := round.ValidMoves()
+	if len(moves) == 0 {
+		fmt.Printf("No movie can follow %s\n", movieLabel(g, round.Current()))
+		if bot {
+			fmt.Println("The bot wins!")
+		}
+		return
+	}
+	if bot {
+		fmt.Println("The bot wins!")
+	}
+
+	fmt.Printf("%s could have been followed by:\n", movieLabel(g, round.Current()))
+	for _, move := range moves[:min(len(moves), 5)] {
+		fmt.Printf("  %s through %s\n", movieLabel(g, move.MovieID), linksLabel(round, round.Current(), move))
+	}
+}
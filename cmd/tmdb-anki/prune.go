@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/anki"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type pruneOptions struct {
+	deckOptions
+	dryRun bool
+}
+
+func newPruneCommand(global *globalOptions) *cobra.Command {
+	opts := &pruneOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove Anki notes for movies that are no longer part of the cast's movies",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			if err := opts.apply(cmd, config); err != nil {
+				return err
+			}
+			return runPrune(config, opts)
+		},
+	}
+
+	opts.register(cmd)
+	cmd.Flags().BoolVarP(&opts.dryRun, "dry-run", "n", false, "only print the notes that would be removed")
+
+	return cmd
+}
+
+func runPrune(config *tmdbankigenerator.Config, opts *pruneOptions) error {
+	movies, err := castMovies(config)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[int]bool, len(movies))
+	for _, movie := range movies {
+		wanted[movie.ID] = true
+	}
+
+	client, err := anki.NewAnkiClient(config.Deck)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to ankiconnect")
+	}
+
+	notes, err := client.GetAllMovies()
+	if err != nil {
+		return errors.Wrap(err, "failed to get notes")
+	}
+
+	keepIds := []int64{}
+	for _, note := range notes {
+		if wanted[note.TMDbID] {
+			keepIds = append(keepIds, *note.NoteID)
+			continue
+		}
+
+		fmt.Printf("removing %s (tmdb:%d)\n", note.MovieTitle, note.TMDbID)
+	}
+
+	fmt.Printf("%d of %d notes to remove\n", len(notes)-len(keepIds), len(notes))
+
+	if opts.dryRun {
+		return nil
+	}
+
+	return client.RemoveUnusedIDs(keepIds)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newResolveCommand(global *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "resolve [name...]",
+		Short: "Print the TMDB people the cast list or the given names resolve to",
+		Long: `Print the TMDB people the cast list resolves to, or the given names when
+there are any. Names are matched ignoring case, accents and punctuation,
+then against also-known-as names in the database and finally allowing a
+typo or two.
+
+Ambiguous names are listed with their candidates, pin the right one in
+the [cast.pins] table of the config:
+
+  [cast.pins]
+  "Tom Hardy" = 2524`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runResolve(config, args)
+		},
+	}
+}
+
+func runResolve(config *tmdbankigenerator.Config, names []string) error {
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LIST\tNAME\tID\tTMDB NAME\tDEPARTMENT\tPOPULARITY\tMATCH")
+	printResolutions := func(list string, resolutions []tmdbankigenerator.Resolution) {
+		for _, r := range resolutions {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%.1f\t%s\n", list, r.Name, r.Person.ID, r.Person.Name, r.Person.KnownForDepartment, r.Person.Popularity, r.Match)
+		}
+	}
+
+	if len(names) > 0 {
+		export, err := config.Exports.Find(tmdbankigenerator.PersonExport)
+		if err != nil {
+			return err
+		}
+
+		candidates, err := tmdbankigenerator.LoadPersonCandidates(export.Path, db)
+		if err != nil {
+			return err
+		}
+
+		resolutions, err := tmdbankigenerator.NewPersonResolver(candidates).ResolveAll(names, config.Cast.Pins)
+		if err != nil {
+			return err
+		}
+		printResolutions("-", resolutions)
+
+		return w.Flush()
+	}
+
+	cast, extraCast, err := tmdbankigenerator.ResolveCast(config, db)
+	if err != nil {
+		return err
+	}
+	printResolutions("people", cast)
+	printResolutions("extra", extraCast)
+
+	return w.Flush()
+}
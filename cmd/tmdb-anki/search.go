@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type searchOptions struct {
+	people bool
+	movies bool
+	limit  int
+}
+
+func newSearchCommand(global *globalOptions) *cobra.Command {
+	opts := &searchOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "search <query>...",
+		Short: "Search the database for people and movies by name",
+		Long: `Search the database for people by name and also-known-as names, and
+for movies by title and original title. Every word of the query must start
+a word of the name, ignoring case and accents, so "tom han" finds Tom Hanks.
+
+Exact matches come first, then names matching every word, then matches on
+an alias or original title. Within each, matches are ranked by how well
+they match, with the name or title weighted over aliases, then by
+popularity.
+
+Search needs tmdb-anki built with -tags sqlite_fts5.`,
+		Args: usageArgs(cobra.MinimumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runSearch(config, strings.Join(args, " "), opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.people, "people", false, "only search people")
+	cmd.Flags().BoolVar(&opts.movies, "movies", false, "only search movies")
+	cmd.Flags().IntVarP(&opts.limit, "limit", "n", 20, "maximum number of people and of movies to print, 0 prints all")
+
+	return cmd
+}
+
+func runSearch(config *tmdbankigenerator.Config, query string, opts *searchOptions) error {
+	if opts.limit < 0 {
+		return usageError{fmt.Errorf("--limit must not be negative, got %d", opts.limit)}
+	}
+	if !opts.people && !opts.movies {
+		opts.people, opts.movies = true, true
+	}
+
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	if opts.people {
+		people, err := db.SearchPeople(query, opts.limit)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, "ID\tNAME\tDEPARTMENT\tPOPULARITY\tMATCH\tALSO KNOWN AS")
+		for _, person := range people {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%.1f\t%s\t%s\n", person.ID, person.Name, person.KnownForDepartment, person.Popularity, person.Match, strings.Join(person.AlsoKnownAs, ", "))
+		}
+		if len(people) == 0 {
+			fmt.Fprintln(w, "no people found")
+		}
+	}
+
+	if opts.people && opts.movies {
+		fmt.Fprintln(w)
+	}
+
+	if opts.movies {
+		movies, err := db.SearchMovies(query, opts.limit)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, "ID\tTITLE\tYEAR\tPOPULARITY\tMATCH\tORIGINAL TITLE")
+		for _, movie := range movies {
+			year := ""
+			if !movie.ReleaseDate.IsZero() {
+				year = movie.ReleaseDate.Format("2006")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%.1f\t%s\t%s\n", movie.ID, movie.Title, year, movie.Popularity, movie.Match, movie.OriginalTitle)
+		}
+		if len(movies) == 0 {
+			fmt.Fprintln(w, "no movies found")
+		}
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type statsOptions struct {
+	json bool
+	top  int
+}
+
+// statsReport is what the stats command prints.
+type statsReport struct {
+	*tmdbankigenerator.DatasetStats
+	// Notes sync would write for the cast list, nil if the cast couldn't be
+	// resolved
+	CastMovies *int `json:"cast_movies"`
+}
+
+func newStatsCommand(global *globalOptions) *cobra.Command {
+	opts := &statsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Print what the database holds and what it lacks",
+		Long: `Print the rows of each table, the credits per job type, the movies per
+language and decade, the most credited people and the movies missing a
+release date or poster and people missing images. Also prints how many
+movies, and so notes, the cast list of the config produces.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runStats(config, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.json, "json", false, "print the report as JSON")
+	cmd.Flags().IntVarP(&opts.top, "top", "n", 10, "number of most credited people to print")
+
+	return cmd
+}
+
+func runStats(config *tmdbankigenerator.Config, opts *statsOptions) error {
+	if opts.top < 0 {
+		return usageError{fmt.Errorf("--top must not be negative, got %d", opts.top)}
+	}
+
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	stats, err := db.Stats(opts.top)
+	if err != nil {
+		return err
+	}
+	report := statsReport{DatasetStats: stats}
+
+	// Stats are still useful without the person export the cast is
+	// resolved against
+	if ids, extraIds, err := tmdbankigenerator.GetCastIDs(config, db); err != nil {
+		log.Printf("Warning: unable to resolve the cast list: %v", err)
+	} else {
+		movies, err := castMoviesFrom(config, db, ids, extraIds)
+		if err != nil {
+			return err
+		}
+		report.CastMovies = new(int)
+		*report.CastMovies = len(movies)
+	}
+
+	if opts.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	printStats(w, report)
+	return w.Flush()
+}
+
+func printStats(w io.Writer, report statsReport) {
+	fmt.Fprintln(w, "TABLE\tROWS")
+	for _, count := range report.Tables {
+		fmt.Fprintf(w, "%s\t%d\n", count.Table, count.Rows)
+	}
+
+	fmt.Fprintln(w, "\nJOB TYPE\tCREDITS")
+	for _, count := range report.CreditsByJobType {
+		fmt.Fprintf(w, "%s\t%d\n", count.JobType, count.Credits)
+	}
+
+	fmt.Fprintln(w, "\nLANGUAGE\tMOVIES")
+	for _, count := range report.MoviesByLanguage {
+		language := string(count.Language)
+		if language == "" {
+			language = "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\n", language, count.Movies)
+	}
+
+	fmt.Fprintln(w, "\nDECADE\tMOVIES")
+	for _, count := range report.MoviesByDecade {
+		fmt.Fprintf(w, "%ds\t%d\n", count.Decade, count.Movies)
+	}
+
+	fmt.Fprintln(w, "\nPERSON\tID\tMOVIES\tCREDITS")
+	for _, person := range report.TopPeople {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", person.Name, person.ID, person.Movies, person.Credits)
+	}
+
+	fmt.Fprintln(w, "\nGAP\tROWS")
+	fmt.Fprintf(w, "movies without release date\t%d\n", report.Gaps.MoviesWithoutReleaseDate)
+	fmt.Fprintf(w, "movies without poster\t%d\n", report.Gaps.MoviesWithoutPoster)
+	fmt.Fprintf(w, "people without images\t%d\n", report.Gaps.PeopleWithoutImages)
+
+	if report.CastMovies != nil {
+		fmt.Fprintf(w, "\nCast list movies:\t%d\n", *report.CastMovies)
+	}
+}
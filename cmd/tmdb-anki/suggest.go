@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"text/tabwriter"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type suggestOptions struct {
+	graphOptions
+	count      int
+	centrality string
+	samples    int
+	write      bool
+}
+
+func newSuggestCommand(global *globalOptions) *cobra.Command {
+	opts := &suggestOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "suggest",
+		Short: "Suggest people to add to the cast list, by the links their movies add",
+		Long: `Suggest the people worth learning next: the ones whose popular movies
+link the most pairs of movies that no one in the cast and extra lists
+already links. Each suggestion counts the links of the ones before it as
+known, so they complement each other.
+
+With --centrality degree every new pair counts the same. With betweenness
+pairs of movies that many shortest chains between movies pass through
+count more, estimated from chains starting at --samples movies.
+
+--write adds the suggestions to cast.people in the config, keeping its
+comments, and pins each name to the suggested person in cast.pins.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			return runSuggest(config, global.configPath, opts)
+		},
+	}
+
+	opts.register(cmd, false, 20)
+	cmd.Flags().IntVarP(&opts.count, "count", "n", 10, "number of people to suggest")
+	cmd.Flags().StringVar(&opts.centrality, "centrality", string(graph.CentralityDegree), "how to weigh the links people add: degree or betweenness")
+	cmd.Flags().IntVar(&opts.samples, "samples", 500, "number of movies betweenness is estimated from, 0 uses every movie")
+	cmd.Flags().BoolVar(&opts.write, "write", false, "add the suggested people to cast.people and cast.pins in the config")
+
+	return cmd
+}
+
+func runSuggest(config *tmdbankigenerator.Config, configPath string, opts *suggestOptions) error {
+	if opts.count <= 0 {
+		return usageError{fmt.Errorf("--count must be positive, got %d", opts.count)}
+	}
+	if opts.samples < 0 {
+		return usageError{fmt.Errorf("--samples must not be negative, got %d", opts.samples)}
+	}
+	centrality := graph.Centrality(opts.centrality)
+	if centrality != graph.CentralityDegree && centrality != graph.CentralityBetweenness {
+		return usageError{fmt.Errorf("unknown centrality %q, use degree or betweenness", opts.centrality)}
+	}
+	query, err := opts.query()
+	if err != nil {
+		return err
+	}
+
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	ids, extraIds, err := tmdbankigenerator.GetCastIDs(config, db)
+	if err != nil {
+		return err
+	}
+
+	g, err := graph.Load(db, query)
+	if err != nil {
+		return errors.Wrap(err, "failed to load graph")
+	}
+
+	suggestions, err := g.Suggest(graph.SuggestOptions{
+		Known:      append(ids, extraIds...),
+		Count:      opts.count,
+		Centrality: centrality,
+		Samples:    opts.samples,
+		Rand:       rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PERSON\tID\tPOPULARITY\tMOVIES\tNEW LINKS\tGAIN\tCENTRALITY")
+	names := make([]string, len(suggestions))
+	pins := make(map[string]int, len(suggestions))
+	for i, suggestion := range suggestions {
+		person, _ := g.Person(suggestion.PersonID)
+		names[i] = person.Name
+		pins[person.Name] = person.ID
+		fmt.Fprintf(w, "%s\t%d\t%.1f\t%d\t%d\t%.1f\t%.1f\n", person.Name, person.ID, person.Popularity, suggestion.Movies, suggestion.NewLinks, suggestion.Gain, suggestion.Centrality)
+	}
+	if len(suggestions) == 0 {
+		fmt.Fprintln(w, "no one links movies the cast doesn't")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !opts.write || len(names) == 0 {
+		return nil
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	out, err := tmdbankigenerator.AddToCast(configPath, src, "people", names, pins)
+	if err != nil {
+		return errors.Wrap(err, "failed to add the suggestions to the config")
+	}
+	if err := os.WriteFile(configPath, out, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %d people to cast.people in %s, pinned to their TMDB IDs\n", len(names), configPath)
+	return nil
+}
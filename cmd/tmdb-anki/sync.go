@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/JonasRothmann/ankiconnect"
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/anki"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+type deckOptions struct {
+	deck          string
+	minPopularity int
+	genres        []string
+	excludeGenres []string
+	topCast       int
+	characters    bool
+}
+
+func (o *deckOptions) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.deck, "deck", "", "Anki deck to write to, overrides deck from the config")
+	cmd.Flags().IntVar(&o.minPopularity, "min-popularity", 0, "only show people above this TMDB popularity on notes, overrides min_popularity from the config")
+	cmd.Flags().StringSliceVar(&o.genres, "genre", nil, "only include movies of these genres, overrides genres.include from the config")
+	cmd.Flags().StringSliceVar(&o.excludeGenres, "exclude-genre", nil, "leave out movies of these genres, overrides genres.exclude from the config")
+	cmd.Flags().IntVar(&o.topCast, "top-cast", 0, "only show the first N billed cast members besides the cast list, 0 shows all, overrides top_cast from the config")
+	cmd.Flags().BoolVar(&o.characters, "characters", false, "show the characters cast members play, overrides show_characters from the config")
+}
+
+// apply overrides the config with the flags that were set.
+func (o *deckOptions) apply(cmd *cobra.Command, config *tmdbankigenerator.Config) error {
+	for _, name := range append(slices.Clone(o.genres), o.excludeGenres...) {
+		if _, ok := tmdbankigenerator.LookupGenre(name); !ok {
+			return usageError{fmt.Errorf("unknown genre %q, TMDB genres are: %s", name, strings.Join(tmdbankigenerator.GenreNames(tmdbankigenerator.TMDBGenres), ", "))}
+		}
+	}
+
+	if o.topCast < 0 {
+		return usageError{fmt.Errorf("--top-cast must not be negative, got %d", o.topCast)}
+	}
+
+	if cmd.Flags().Changed("deck") {
+		config.Deck = o.deck
+	}
+	if cmd.Flags().Changed("min-popularity") {
+		config.MinPopularity = o.minPopularity
+	}
+	if cmd.Flags().Changed("genre") {
+		config.Genres.Include = o.genres
+	}
+	if cmd.Flags().Changed("exclude-genre") {
+		config.Genres.Exclude = o.excludeGenres
+	}
+	if cmd.Flags().Changed("top-cast") {
+		config.TopCast = o.topCast
+	}
+	if cmd.Flags().Changed("characters") {
+		config.ShowCharacters = o.characters
+	}
+
+	return nil
+}
+
+type syncOptions struct {
+	deckOptions
+	concurrency int
+	prune       bool
+}
+
+func newSyncCommand(global *globalOptions) *cobra.Command {
+	opts := &syncOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Create and update Anki notes for the movies of the configured cast",
+		Long: `Create and update one Anki note per movie of the people in the cast
+list, clozing the cast members. Requires Anki to be running with the
+AnkiConnect add-on.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := global.loadConfig()
+			if err != nil {
+				return err
+			}
+			if err := opts.apply(cmd, config); err != nil {
+				return err
+			}
+			return runSync(config, opts)
+		},
+	}
+
+	opts.register(cmd)
+	cmd.Flags().IntVar(&opts.concurrency, "concurrency", 5, "number of notes to upsert in parallel")
+	cmd.Flags().BoolVar(&opts.prune, "prune", true, "remove notes for movies that are no longer part of the cast's movies")
+
+	return cmd
+}
+
+func runSync(config *tmdbankigenerator.Config, opts *syncOptions) error {
+	if opts.concurrency <= 0 {
+		return usageError{fmt.Errorf("--concurrency must be positive, got %d", opts.concurrency)}
+	}
+
+	result, err := castMovies(config)
+	if err != nil {
+		return err
+	}
+
+	client, err := anki.NewAnkiClient(config.Deck)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to ankiconnect")
+	}
+
+	g := errgroup.Group{}
+	g.SetLimit(opts.concurrency)
+	mu := sync.Mutex{}
+
+	moviesToKeep := make([]int64, 0, len(result))
+
+	for _, movie := range result {
+		note := movieNote(movie, config.ShowCharacters)
+
+		g.Go(func() error {
+			id, err := client.UpsertMovieNote(&note)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			moviesToKeep = append(moviesToKeep, id)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return errors.Wrap(err, "failed to upsert notes")
+	}
+
+	if opts.prune {
+		return client.RemoveUnusedIDs(moviesToKeep)
+	}
+
+	return nil
+}
+
+// castMovies returns the movies of the cast list that pass the genre filter,
+// in cast list order and with each person listed once per movie, limited to
+// the top billed cast.
+func castMovies(config *tmdbankigenerator.Config) ([]tmdbankigenerator.Movie, error) {
+	db, err := tmdbankigenerator.NewDatabase(config.Database.Options(true))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to start database")
+	}
+	defer db.Close()
+
+	ids, extraIds, err := tmdbankigenerator.GetCastIDs(config, db)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println(strings.Join(lo.Map(ids, func(id int, index int) string {
+		return strconv.Itoa(id)
+	}), ", "))
+
+	return castMoviesFrom(config, db, ids, extraIds)
+}
+
+// castMoviesFrom is castMovies for people that were already resolved.
+func castMoviesFrom(config *tmdbankigenerator.Config, db *tmdbankigenerator.Database, ids, extraIds []int) ([]tmdbankigenerator.Movie, error) {
+	movies, err := db.QueryMovies(tmdbankigenerator.MovieQuery{
+		PersonIDs:           ids,
+		ExtraIDs:            extraIds,
+		MinPersonPopularity: float32(config.MinPopularity),
+		IncludeAdult:        true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get movies")
+	}
+
+	result := []tmdbankigenerator.Movie{}
+	addedMovieIDs := make(map[int]bool)
+
+	personToMovies := make(map[int][]tmdbankigenerator.Movie)
+	for _, movie := range movies {
+		if !config.Genres.Match(movie.Genres) {
+			continue
+		}
+
+		movie.Persons = tmdbankigenerator.TopBilledCast(movie.Persons, config.TopCast)
+
+		for _, person := range movie.Persons {
+			personToMovies[person.ID] = append(personToMovies[person.ID], movie)
+		}
+	}
+
+	for _, id := range ids {
+		if movies, ok := personToMovies[id]; ok {
+			for _, movie := range movies {
+				if !addedMovieIDs[movie.ID] {
+					result = append(result, movie)
+					addedMovieIDs[movie.ID] = true
+				}
+			}
+		} else {
+			log.Printf("Warning: Person ID %d not found in map\n", id)
+		}
+	}
+
+	return result, nil
+}
+
+func movieNote(movie tmdbankigenerator.Movie, showCharacters bool) anki.MovieNote {
+	note := anki.MovieNote{
+		MovieTitle:  movie.Title,
+		ReleaseDate: movie.ReleaseDate,
+		TMDbID:      movie.ID,
+		Popularity:  movie.Popularity,
+		Genres:      tmdbankigenerator.GenreNames(movie.Genres),
+	}
+
+	for _, image := range movie.Images {
+		after, found := strings.CutPrefix(image.Path, "/")
+		if !found {
+			fmt.Printf("no image in %s\n", movie.Title)
+			continue
+		}
+		note.Pictures = append(note.Pictures, ankiconnect.Picture{
+			Filename: after,
+			URL:      fmt.Sprintf("https://image.tmdb.org/t/p/w500/%s", after),
+			Fields:   []string{},
+		})
+	}
+
+	for _, person := range movie.Persons {
+		cloze := anki.MaybeCloze{
+			IsCloze: person.InList,
+			Content: person.Name,
+		}
+
+		switch person.JobType {
+		case tmdbankigenerator.JobTypeCast:
+			if showCharacters {
+				cloze.Character = person.Character
+			}
+			note.Cast = append(note.Cast, cloze)
+		case tmdbankigenerator.JobTypeWriter:
+			note.Writer = append(note.Writer, cloze)
+		case tmdbankigenerator.JobTypeComposer, tmdbankigenerator.JobTypeComposer2, tmdbankigenerator.JobTypeComposer3, tmdbankigenerator.JobTypeComposer4:
+			note.Composer = append(note.Composer, cloze)
+		case tmdbankigenerator.JobTypeCinematographer:
+			note.Cinematograper = append(note.Cinematograper, cloze)
+		case tmdbankigenerator.JobTypeDirector:
+			note.Director = append(note.Director, cloze)
+		}
+	}
+
+	return note
+}
@@ -0,0 +1,473 @@
+package tmdbankigenerator
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+const DefaultConfigPath = "config.toml"
+
+type Config struct {
+	Deck          string `toml:"deck"`
+	MinPopularity int    `toml:"min_popularity"`
+	// Cast members billed below this are left off notes, unless they're in
+	// the cast list. 0 shows the whole cast.
+	TopCast        int            `toml:"top_cast"`
+	ShowCharacters bool           `toml:"show_characters"`
+	Genres         GenreFilter    `toml:"genres"`
+	Database       DatabaseConfig `toml:"database"`
+	Exports        ExportsConfig  `toml:"exports"`
+	Cache          CacheConfig    `toml:"cache"`
+	Cast           CastConfig     `toml:"cast"`
+}
+
+type DatabaseConfig struct {
+	Path string `toml:"path"`
+	// Write-ahead logging lets commands read while index writes
+	WAL bool `toml:"wal"`
+	// How long to wait for another process holding a lock before failing
+	BusyTimeoutMS int `toml:"busy_timeout_ms"`
+}
+
+// Options returns how to open the configured database.
+func (c DatabaseConfig) Options(readOnly bool) DatabaseOptions {
+	return DatabaseOptions{
+		Path:        c.Path,
+		ReadOnly:    readOnly,
+		WAL:         c.WAL,
+		BusyTimeout: time.Duration(c.BusyTimeoutMS) * time.Millisecond,
+	}
+}
+
+type ExportsConfig struct {
+	// Directory searched for the newest movie_ids_MM_DD_YYYY.json(.gz) and
+	// person_ids_MM_DD_YYYY.json(.gz)
+	Dir string `toml:"dir"`
+	// Explicit export files, used instead of searching Dir
+	Movies string `toml:"movies"`
+	People string `toml:"people"`
+	// Exports older than this are reported as stale, 0 never reports them
+	MaxAgeDays int `toml:"max_age_days"`
+}
+
+type CacheConfig struct {
+	// File TMDB responses are cached in
+	Path string `toml:"path"`
+	// Cached responses older than this are fetched again, 0 keeps them forever
+	TTLDays int `toml:"ttl_days"`
+	// The least recently used responses are dropped beyond this many, 0
+	// keeps all of them
+	MaxEntries int `toml:"max_entries"`
+}
+
+type CastConfig struct {
+	People []string `toml:"people"`
+	// These people are clozed but their movies arent added to the list
+	Extra []string `toml:"extra"`
+	// TMDB IDs for names that are ambiguous or spelled differently on TMDB
+	Pins map[string]int `toml:"pins"`
+}
+
+// ConfigError points at the line in the config file that caused a problem.
+// Line is 0 when the problem isn't tied to a single line, e.g. a missing key.
+type ConfigError struct {
+	File string
+	Line int
+	Key  string
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	location := e.File
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	if e.Key != "" {
+		return fmt.Sprintf("%s: %s: %s", location, e.Key, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", location, e.Msg)
+}
+
+// ConfigErrors collects every problem found while validating a config file,
+// so they can all be fixed in one go.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func LoadConfig(path string) (*Config, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return nil, &ConfigError{File: path, Msg: fmt.Sprintf("failed to read config: %s", err)}
+	}
+
+	return ParseConfig(path, src)
+}
+
+func ParseConfig(fileName string, src []byte) (*Config, error) {
+	config := Config{
+		Deck: "Cine2Nerdle",
+		Database: DatabaseConfig{
+			Path:          "data.db",
+			BusyTimeoutMS: 5000,
+		},
+		Exports: ExportsConfig{
+			Dir:        ".",
+			MaxAgeDays: 7,
+		},
+		Cache: CacheConfig{
+			Path:    "tmdb_cache.json",
+			TTLDays: 30,
+		},
+	}
+
+	meta, err := toml.Decode(string(src), &config)
+	if err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &ConfigError{
+				File: fileName,
+				Line: parseErr.Position.Line,
+				Key:  parseErr.LastKey,
+				Msg:  parseErr.Message,
+			}
+		}
+		return nil, &ConfigError{File: fileName, Msg: err.Error()}
+	}
+
+	lines := strings.Split(string(src), "\n")
+
+	var errs ConfigErrors
+	fail := func(line int, key string, format string, args ...any) {
+		errs = append(errs, &ConfigError{
+			File: fileName,
+			Line: line,
+			Key:  key,
+			Msg:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Only report the outermost unknown key, not every key in an unknown table
+	unknown := map[string]bool{}
+	for _, key := range meta.Undecoded() {
+		if len(key) > 1 && unknown[key[:len(key)-1].String()] {
+			unknown[key.String()] = true
+			continue
+		}
+		unknown[key.String()] = true
+		fail(keyLine(lines, key...), key.String(), "unknown key")
+	}
+
+	if strings.TrimSpace(config.Deck) == "" {
+		fail(keyLine(lines, "deck"), "deck", "must not be empty")
+	}
+	if config.MinPopularity < 0 {
+		fail(keyLine(lines, "min_popularity"), "min_popularity", "must not be negative, got %d", config.MinPopularity)
+	}
+	if config.TopCast < 0 {
+		fail(keyLine(lines, "top_cast"), "top_cast", "must not be negative, got %d", config.TopCast)
+	}
+	for _, list := range []struct {
+		key   string
+		names []string
+	}{
+		{"include", config.Genres.Include},
+		{"exclude", config.Genres.Exclude},
+	} {
+		line := keyLine(lines, "genres", list.key)
+		for _, name := range list.names {
+			line = valueLine(lines, line, name)
+			if _, ok := LookupGenre(name); !ok {
+				fail(line, "genres."+list.key, "unknown genre %q, TMDB genres are: %s", name, strings.Join(GenreNames(TMDBGenres), ", "))
+			}
+		}
+	}
+	if strings.TrimSpace(config.Database.Path) == "" {
+		fail(keyLine(lines, "database", "path"), "database.path", "must not be empty")
+	}
+	if config.Database.BusyTimeoutMS < 0 {
+		fail(keyLine(lines, "database", "busy_timeout_ms"), "database.busy_timeout_ms", "must not be negative, got %d", config.Database.BusyTimeoutMS)
+	}
+	if config.Exports.Dir == "" {
+		fail(keyLine(lines, "exports", "dir"), "exports.dir", "must not be empty")
+	}
+	if config.Exports.MaxAgeDays < 0 {
+		fail(keyLine(lines, "exports", "max_age_days"), "exports.max_age_days", "must not be negative, got %d", config.Exports.MaxAgeDays)
+	}
+	if strings.TrimSpace(config.Cache.Path) == "" {
+		fail(keyLine(lines, "cache", "path"), "cache.path", "must not be empty")
+	}
+	if config.Cache.TTLDays < 0 {
+		fail(keyLine(lines, "cache", "ttl_days"), "cache.ttl_days", "must not be negative, got %d", config.Cache.TTLDays)
+	}
+	if config.Cache.MaxEntries < 0 {
+		fail(keyLine(lines, "cache", "max_entries"), "cache.max_entries", "must not be negative, got %d", config.Cache.MaxEntries)
+	}
+	if len(config.Cast.People) == 0 {
+		fail(keyLine(lines, "cast", "people"), "cast.people", "must contain at least one name")
+	}
+
+	seen := map[string]string{}
+	for _, list := range []struct {
+		key   string
+		names []string
+	}{
+		{"people", config.Cast.People},
+		{"extra", config.Cast.Extra},
+	} {
+		key := "cast." + list.key
+		line := keyLine(lines, "cast", list.key)
+
+		for _, name := range list.names {
+			line = valueLine(lines, line, name)
+
+			if strings.TrimSpace(name) == "" {
+				fail(line, key, "empty name")
+				continue
+			}
+
+			normalized := strings.ToLower(name)
+			if previous, ok := seen[normalized]; ok {
+				fail(line, key, "%q is already listed in cast.%s", name, previous)
+				continue
+			}
+			seen[normalized] = list.key
+		}
+	}
+
+	for name, id := range config.Cast.Pins {
+		line := keyLine(lines, "cast", "pins", name)
+		if id <= 0 {
+			fail(line, "cast.pins", "%q must be pinned to a positive TMDB ID, got %d", name, id)
+		}
+		if !slices.Contains(config.Cast.People, name) && !slices.Contains(config.Cast.Extra, name) {
+			fail(line, "cast.pins", "%q is not listed in cast.people or cast.extra", name)
+		}
+	}
+
+	if len(errs) > 0 {
+		slices.SortStableFunc(errs, func(a, b *ConfigError) int {
+			return cmp.Compare(a.Line, b.Line)
+		})
+		return nil, errs
+	}
+
+	return &config, nil
+}
+
+// AddToCast adds names to the cast.people or cast.extra list of a config,
+// editing its source so comments and layout are kept. Names in pins are also
+// pinned to their TMDB ID in cast.pins, so they resolve to the same person
+// however many share the name. The result is checked like ParseConfig checks
+// a config, so names already listed or pinned fail.
+func AddToCast(fileName string, src []byte, list string, names []string, pins map[string]int) ([]byte, error) {
+	if list != "people" && list != "extra" {
+		return nil, fmt.Errorf("unknown cast list %q", list)
+	}
+	if len(names) == 0 {
+		return src, nil
+	}
+
+	lines := strings.Split(string(src), "\n")
+	start := keyLine(lines, "cast", list)
+	if start == 0 {
+		return nil, &ConfigError{File: fileName, Key: "cast." + list, Msg: "not found, add the list first"}
+	}
+
+	// Find the closing bracket of the array, and the last character before
+	// it that isn't a space or comment
+	type position struct{ line, column int }
+	var closing, last position
+	depth := 0
+	var quote byte
+scan:
+	for i := start - 1; i < len(lines); i++ {
+		line := lines[i]
+		column := 0
+		if i == start-1 {
+			_, value, _ := strings.Cut(line, "=")
+			column = len(line) - len(value)
+		}
+		for ; column < len(line); column++ {
+			c := line[column]
+			switch {
+			case quote != 0:
+				if c == '\\' && quote == '"' {
+					column++
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '#':
+				continue scan
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '[':
+				depth++
+			case c == ']':
+				depth--
+				if depth == 0 {
+					closing = position{i, column}
+					break scan
+				}
+			}
+			if c != ' ' && c != '\t' {
+				last = position{i, column}
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, &ConfigError{File: fileName, Line: start, Key: "cast." + list, Msg: "unterminated list"}
+	}
+
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	values := strings.Join(quoted, ", ")
+
+	lastChar := lines[last.line][last.column]
+	if strings.TrimSpace(lines[closing.line][:closing.column]) == "" {
+		// One value per line or a few per line, with the bracket on its own
+		// line: add a line of names in the indentation of the last one
+		lastLine := lines[last.line]
+		indent := lastLine[:len(lastLine)-len(strings.TrimLeft(lastLine, " \t"))]
+		if lastChar == '[' {
+			indent += "  "
+		} else if lastChar != ',' {
+			lines[last.line] = lastLine[:last.column+1] + "," + lastLine[last.column+1:]
+		}
+		lines = slices.Insert(lines, closing.line, indent+values+",")
+	} else {
+		line := lines[closing.line]
+		switch lastChar {
+		case '[':
+		case ',':
+			values = " " + values
+		default:
+			values = ", " + values
+		}
+		lines[closing.line] = line[:closing.column] + values + line[closing.column:]
+	}
+
+	lines, err := addPins(fileName, lines, names, pins)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []byte(strings.Join(lines, "\n"))
+	if _, err := ParseConfig(fileName, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// addPins adds the pins of names to the [cast.pins] table, after its last
+// line, or adds the table at the end.
+func addPins(fileName string, lines []string, names []string, pins map[string]int) ([]string, error) {
+	var added []string
+	for _, name := range names {
+		if id, ok := pins[name]; ok {
+			added = append(added, fmt.Sprintf("%q = %d", name, id))
+		}
+	}
+	if len(added) == 0 {
+		return lines, nil
+	}
+
+	start := keyLine(lines, "cast", "pins")
+	if start == 0 {
+		// Keep a trailing newline at the end
+		end := len(lines)
+		if end > 0 && lines[end-1] == "" {
+			end--
+		}
+		table := append([]string{"", "[cast.pins]"}, added...)
+		return slices.Insert(lines, end, table...), nil
+	}
+	if !strings.HasPrefix(strings.TrimSpace(lines[start-1]), "[") {
+		return nil, &ConfigError{File: fileName, Line: start, Key: "cast.pins", Msg: "only a [cast.pins] table can be added to, not an inline table"}
+	}
+
+	// The table ends at the next table, the pins go after its last line
+	// that isn't blank
+	last := start - 1
+	for i := start; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "[") {
+			break
+		}
+		if line != "" {
+			last = i
+		}
+	}
+	return slices.Insert(lines, last+1, added...), nil
+}
+
+// keyLine returns the 1-based line on which a (possibly nested) key is
+// assigned, or 0 if it can't be found. Only the plain `[table]` and
+// `key = value` forms are recognised, which is all the config uses.
+func keyLine(lines []string, key ...string) int {
+	if len(key) == 0 {
+		return 0
+	}
+
+	table := strings.Join(key[:len(key)-1], ".")
+	name := key[len(key)-1]
+
+	current := ""
+	for i, line := range lines {
+		line, _, _ = strings.Cut(line, "#")
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "[") && !strings.HasPrefix(line, "[[") {
+			current = strings.TrimSpace(strings.Trim(line, "[]"))
+			if current == strings.Join(key, ".") {
+				return i + 1
+			}
+			continue
+		}
+
+		if current != table {
+			continue
+		}
+
+		if before, _, found := strings.Cut(line, "="); found && strings.Trim(strings.TrimSpace(before), `"`) == name {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// valueLine returns the first line at or after start that contains value as
+// a quoted string, falling back to start.
+func valueLine(lines []string, start int, value string) int {
+	if start < 1 {
+		return start
+	}
+
+	quoted := fmt.Sprintf("%q", value)
+	for i := start - 1; i < len(lines); i++ {
+		if strings.Contains(lines[i], quoted) {
+			return i + 1
+		}
+	}
+
+	return start
+}
@@ -0,0 +1,232 @@
+package tmdbankigenerator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `deck = "Test"
+min_popularity = 10
+
+[database]
+path = "test.db"
+
+[exports]
+dir = "exports"
+max_age_days = 3
+
+[cast]
+people = [
+  "Tim Burton",
+  "David Lynch",
+]
+extra = ["Tom Hanks"]
+`
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig("config.toml", []byte(testConfig))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	if config.Deck != "Test" {
+		t.Errorf("expected deck %q, got %q", "Test", config.Deck)
+	}
+	if config.MinPopularity != 10 {
+		t.Errorf("expected min_popularity 10, got %d", config.MinPopularity)
+	}
+	if opts := config.Database.Options(true); opts.Path != "test.db" || !opts.ReadOnly || opts.BusyTimeout != 5*time.Second {
+		t.Errorf("unexpected database options: %+v", opts)
+	}
+	if len(config.Cast.People) != 2 || len(config.Cast.Extra) != 1 {
+		t.Errorf("unexpected cast: %+v", config.Cast)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		key  string
+	}{
+		{
+			name: "Syntax error",
+			src:  "deck = \"Test\"\nmin_popularity = = 3\n",
+			line: 2,
+		},
+		{
+			name: "Unknown key",
+			src:  testConfig + "\n[anki]\ndeck = \"Other\"\n",
+			line: 18,
+			key:  "anki",
+		},
+		{
+			name: "Negative popularity",
+			src:  "min_popularity = -1\n" + testConfig[len("deck = \"Test\"\nmin_popularity = 10\n"):],
+			line: 1,
+			key:  "min_popularity",
+		},
+		{
+			name: "Negative top cast",
+			src:  "top_cast = -5\n" + testConfig,
+			line: 1,
+			key:  "top_cast",
+		},
+		{
+			name: "Negative busy timeout",
+			src:  strings.Replace(testConfig, "path = \"test.db\"\n", "path = \"test.db\"\nbusy_timeout_ms = -1\n", 1),
+			line: 6,
+			key:  "database.busy_timeout_ms",
+		},
+		{
+			name: "Negative max age",
+			src:  strings.Replace(testConfig, "max_age_days = 3\n", "max_age_days = -1\n", 1),
+			line: 9,
+			key:  "exports.max_age_days",
+		},
+		{
+			name: "Pin for unlisted name",
+			src:  testConfig + "\n[cast.pins]\n\"Tom Hardy\" = 2524\n",
+			line: 19,
+			key:  "cast.pins",
+		},
+		{
+			name: "Unknown genre",
+			src:  testConfig + "\n[genres]\ninclude = [\"Drama\", \"Sci-Fi\"]\n",
+			line: 19,
+			key:  "genres.include",
+		},
+		{
+			name: "Duplicate name",
+			src:  testConfig[:len(testConfig)-len("extra = [\"Tom Hanks\"]\n")] + "extra = [\n  \"Tom Hanks\",\n  \"david lynch\",\n]\n",
+			line: 18,
+			key:  "cast.extra",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseConfig("config.toml", []byte(test.src))
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+
+			var configErr *ConfigError
+			var configErrs ConfigErrors
+			if errors.As(err, &configErrs) {
+				configErr = configErrs[0]
+			} else if !errors.As(err, &configErr) {
+				t.Fatalf("expected a ConfigError, got %T: %v", err, err)
+			}
+
+			if configErr.Line != test.line {
+				t.Errorf("expected line %d, got %d (%v)", test.line, configErr.Line, err)
+			}
+			if test.key != "" && configErr.Key != test.key {
+				t.Errorf("expected key %q, got %q", test.key, configErr.Key)
+			}
+		})
+	}
+}
+
+func TestAddToCast(t *testing.T) {
+	const header = "deck = \"Test\"\n\n[cast]\n"
+
+	tests := []struct {
+		name     string
+		src      string
+		list     string
+		expected string
+	}{
+		{
+			name:     "Trailing comma",
+			src:      header + "people = [\n  \"Tim Burton\", \"David Lynch\", # directors\n]\n",
+			list:     "people",
+			expected: header + "people = [\n  \"Tim Burton\", \"David Lynch\", # directors\n  \"Bill Murray\", \"Pilou Asbæk\",\n]\n",
+		},
+		{
+			name:     "No trailing comma",
+			src:      header + "people = [\n    \"Tim Burton\"\n]\n",
+			list:     "people",
+			expected: header + "people = [\n    \"Tim Burton\",\n    \"Bill Murray\", \"Pilou Asbæk\",\n]\n",
+		},
+		{
+			name:     "One line",
+			src:      header + "people = [\"Tim Burton\"]\nextra = [] # clozed only\n",
+			list:     "extra",
+			expected: header + "people = [\"Tim Burton\"]\nextra = [\"Bill Murray\", \"Pilou Asbæk\"] # clozed only\n",
+		},
+		{
+			name:     "Brackets in names",
+			src:      header + "people = [\"Tim [Burton]\", 'David ]Lynch']\n",
+			list:     "people",
+			expected: header + "people = [\"Tim [Burton]\", 'David ]Lynch', \"Bill Murray\", \"Pilou Asbæk\"]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := AddToCast("config.toml", []byte(tt.src), tt.list, []string{"Bill Murray", "Pilou Asbæk"}, nil)
+			if err != nil {
+				t.Fatalf("AddToCast failed: %v", err)
+			}
+			if string(out) != tt.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tt.expected, out)
+			}
+		})
+	}
+
+	t.Run("Already listed", func(t *testing.T) {
+		_, err := AddToCast("config.toml", []byte(testConfig), "extra", []string{"David Lynch"}, nil)
+		var errs ConfigErrors
+		if !errors.As(err, &errs) || len(errs) != 1 || !strings.Contains(errs[0].Msg, "already listed") {
+			t.Errorf("expected a duplicate name to fail, got %v", err)
+		}
+	})
+
+	t.Run("Missing list", func(t *testing.T) {
+		if _, err := AddToCast("config.toml", []byte(header+"people = [\"Tim Burton\"]\n"), "extra", []string{"Bill Murray"}, nil); err == nil {
+			t.Errorf("expected a missing list to fail")
+		}
+	})
+
+	t.Run("Pins", func(t *testing.T) {
+		pins := map[string]int{"Bill Murray": 1532, "Pilou Asbæk": 230566}
+		names := []string{"Bill Murray", "Pilou Asbæk"}
+
+		src := header + "people = [\"Tom Hardy\"]\n\n# ambiguous names\n[cast.pins]\n\"Tom Hardy\" = 2524\n\n[genres]\n"
+		expected := header + "people = [\"Tom Hardy\", \"Bill Murray\", \"Pilou Asbæk\"]\n\n# ambiguous names\n[cast.pins]\n\"Tom Hardy\" = 2524\n\"Bill Murray\" = 1532\n\"Pilou Asbæk\" = 230566\n\n[genres]\n"
+		out, err := AddToCast("config.toml", []byte(src), "people", names, pins)
+		if err != nil {
+			t.Fatalf("AddToCast failed: %v", err)
+		}
+		if string(out) != expected {
+			t.Errorf("expected\n%s\ngot\n%s", expected, out)
+		}
+
+		config, err := ParseConfig("config.toml", out)
+		if err != nil {
+			t.Fatalf("ParseConfig failed: %v", err)
+		}
+		if config.Cast.Pins["Pilou Asbæk"] != 230566 || config.Cast.Pins["Tom Hardy"] != 2524 {
+			t.Errorf("unexpected pins %v", config.Cast.Pins)
+		}
+
+		// Without a table one is added at the end
+		out, err = AddToCast("config.toml", []byte(header+"people = []\n"), "people", names, pins)
+		if err != nil {
+			t.Fatalf("AddToCast failed: %v", err)
+		}
+		expected = header + "people = [\"Bill Murray\", \"Pilou Asbæk\"]\n\n[cast.pins]\n\"Bill Murray\" = 1532\n\"Pilou Asbæk\" = 230566\n"
+		if string(out) != expected {
+			t.Errorf("expected\n%s\ngot\n%s", expected, out)
+		}
+
+		if _, err := AddToCast("config.toml", []byte(header+"people = []\npins = { \"Tom Hardy\" = 2524 }\n"), "people", names, pins); err == nil {
+			t.Errorf("expected an inline pins table to fail")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package tmdbankigenerator
+
+import (
+	"cmp"
+	"slices"
+)
+
+// MovieConnection is a pair of movies sharing people from the cast list.
+type MovieConnection struct {
+	// The movie with the lower ID
+	First Movie
+	// The movie with the higher ID
+	Second Movie
+	// People from the cast list on both movies, in the order they're listed
+	// on First
+	People []MoviePerson
+}
+
+// Key identifies the pair, whichever order its movies were given in.
+func (c MovieConnection) Key() [2]int {
+	return [2]int{c.First.ID, c.Second.ID}
+}
+
+// ConnectionOptions limits the connections MovieConnections returns. The
+// zero value doesn't limit.
+type ConnectionOptions struct {
+	// At most this many connections are returned
+	Limit int
+	// Each movie is in at most this many connections
+	MaxPerMovie int
+}
+
+// MovieConnections returns every pair of movies sharing at least one person
+// marked InList, once per pair. Pairs of popular movies come first, as those
+// come up in games: a pair ranks by its less popular movie, then by its more
+// popular one. Movies are expected to list each person once.
+func MovieConnections(movies []Movie, opts ConnectionOptions) []MovieConnection {
+	byID := make(map[int]Movie, len(movies))
+	personMovies := make(map[int][]int)
+	for _, movie := range movies {
+		if _, ok := byID[movie.ID]; ok {
+			continue
+		}
+		byID[movie.ID] = movie
+		for _, person := range movie.Persons {
+			if person.InList {
+				personMovies[person.ID] = append(personMovies[person.ID], movie.ID)
+			}
+		}
+	}
+
+	pairs := make(map[[2]int]bool)
+	for _, movieIDs := range personMovies {
+		for i, a := range movieIDs {
+			for _, b := range movieIDs[i+1:] {
+				pairs[[2]int{min(a, b), max(a, b)}] = true
+			}
+		}
+	}
+
+	connections := make([]MovieConnection, 0, len(pairs))
+	for pair := range pairs {
+		connection := MovieConnection{First: byID[pair[0]], Second: byID[pair[1]]}
+		for _, person := range connection.First.Persons {
+			if person.InList && slices.ContainsFunc(connection.Second.Persons, func(other MoviePerson) bool {
+				return other.InList && other.ID == person.ID
+			}) {
+				connection.People = append(connection.People, person)
+			}
+		}
+		connections = append(connections, connection)
+	}
+
+	slices.SortFunc(connections, compareConnections)
+
+	perMovie := make(map[int]int)
+	result := []MovieConnection{}
+	for _, connection := range connections {
+		if opts.Limit > 0 && len(result) == opts.Limit {
+			break
+		}
+		if opts.MaxPerMovie > 0 && (perMovie[connection.First.ID] == opts.MaxPerMovie || perMovie[connection.Second.ID] == opts.MaxPerMovie) {
+			continue
+		}
+		perMovie[connection.First.ID]++
+		perMovie[connection.Second.ID]++
+		result = append(result, connection)
+	}
+
+	return result
+}
+
+func compareConnections(a, b MovieConnection) int {
+	aLow, aHigh := minMax(a.First.Popularity, a.Second.Popularity)
+	bLow, bHigh := minMax(b.First.Popularity, b.Second.Popularity)
+	return cmp.Or(
+		cmp.Compare(bLow, aLow),
+		cmp.Compare(bHigh, aHigh),
+		cmp.Compare(a.First.ID, b.First.ID),
+		cmp.Compare(a.Second.ID, b.Second.ID),
+	)
+}
+
+func minMax(a, b float32) (float32, float32) {
+	return min(a, b), max(a, b)
+}
@@ -0,0 +1,52 @@
+package tmdbankigenerator
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMovieConnections(t *testing.T) {
+	person := func(id int, inList bool) MoviePerson {
+		return MoviePerson{JobType: JobTypeCast, InList: inList, Person: Person{ID: id, Name: "Person"}}
+	}
+	movies := []Movie{
+		{ID: 3, Popularity: 50, Persons: []MoviePerson{person(10, true), person(11, true), person(20, false)}},
+		{ID: 1, Popularity: 40, Persons: []MoviePerson{person(11, true), person(10, true)}},
+		{ID: 2, Popularity: 10, Persons: []MoviePerson{person(10, true), person(20, false)}},
+		// Only shares someone off the cast list
+		{ID: 4, Popularity: 90, Persons: []MoviePerson{person(20, false)}},
+		// Listed twice, as when two people bring in the same movie
+		{ID: 2, Popularity: 10, Persons: []MoviePerson{person(10, true), person(20, false)}},
+	}
+
+	keys := func(connections []MovieConnection) [][2]int {
+		keys := make([][2]int, len(connections))
+		for i, connection := range connections {
+			keys[i] = connection.Key()
+		}
+		return keys
+	}
+
+	connections := MovieConnections(movies, ConnectionOptions{})
+	if expected := [][2]int{{1, 3}, {2, 3}, {1, 2}}; !slices.Equal(keys(connections), expected) {
+		t.Fatalf("expected pairs %v, got %v", expected, keys(connections))
+	}
+
+	var people []int
+	for _, person := range connections[0].People {
+		people = append(people, person.ID)
+	}
+	if !slices.Equal(people, []int{11, 10}) {
+		t.Errorf("expected both cast list people in First's order, got %v", people)
+	}
+	if len(connections[1].People) != 1 || connections[1].People[0].ID != 10 {
+		t.Errorf("expected only person 10 to link 2 and 3, got %+v", connections[1].People)
+	}
+
+	if connections := MovieConnections(movies, ConnectionOptions{Limit: 1}); !slices.Equal(keys(connections), [][2]int{{1, 3}}) {
+		t.Errorf("expected the limit to keep the best pair, got %v", keys(connections))
+	}
+	if connections := MovieConnections(movies, ConnectionOptions{MaxPerMovie: 1}); !slices.Equal(keys(connections), [][2]int{{1, 3}}) {
+		t.Errorf("expected each movie in one pair, got %v", keys(connections))
+	}
+}
@@ -3,185 +3,205 @@ package tmdbankigenerator
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var schema = `
-CREATE TABLE IF NOT EXISTS persons (
-    id                  INTEGER PRIMARY KEY,
-    birthday            TEXT,
-    known_for_department TEXT,
-    name                TEXT,
-    also_known_as       TEXT,    -- JSON array for multiple names
-    gender              INTEGER,
-    popularity          REAL,
-    place_of_birth      TEXT,
-    profile_path        TEXT,
-    adult               BOOLEAN,
-    imdb_id             TEXT
-);
-
-CREATE TABLE IF NOT EXISTS movies (
-    id       		INTEGER PRIMARY KEY,
-    title    		TEXT,
-    language 		TEXT,
-    popularity 		FLOAT,
-    runtime  		FLOAT,
-    genres			TEXT,
-    release_date    TEXT,
-    adult 			BOOLEAN
-);
-
-CREATE TABLE IF NOT EXISTS credits (
-    person_id   INTEGER NOT NULL,
-    movie_id    INTEGER NOT NULL,
-    job_type    TEXT NOT NULL,
-
-    FOREIGN KEY(person_id) REFERENCES persons(id),
-    FOREIGN KEY(movie_id) REFERENCES movies(id),
-    PRIMARY KEY(person_id, movie_id, job_type)
-);
-
-CREATE TABLE IF NOT EXISTS person_images (
-    person_id   INTEGER NOT NULL,
-    path        TEXT NOT NULL,
-
-    FOREIGN KEY(person_id) REFERENCES persons(id),
-    PRIMARY KEY(person_id, path)
-);
-
-CREATE TABLE IF NOT EXISTS movie_images (
-    movie_id   INTEGER NOT NULL,
-    path       TEXT NOT NULL,
-
-    FOREIGN KEY(movie_id) REFERENCES movies(id),
-    PRIMARY KEY(movie_id, path)
-);
-
-CREATE UNIQUE INDEX IF NOT EXISTS idx_credits ON credits(person_id, movie_id, job_type);
-`
-
 type Database struct {
 	conn *sqlx.DB
 }
 
-func NewDatabase() (*Database, error) {
-	conn, err := sqlx.Connect("sqlite3", "data.db?_cache=shared&_mode=rwc")
-	if err != nil {
-		return nil, err
+// DatabaseOptions say where the database is and how to open it.
+type DatabaseOptions struct {
+	// File the database is kept in, unused when InMemory
+	Path string
+	// Only read the database. It must exist, and NewDatabase also requires
+	// it to be migrated and to hold movies.
+	ReadOnly bool
+	// Keep the database in memory, for tests. It's gone once closed.
+	InMemory bool
+	// Use a write-ahead log, so readers don't wait for the indexer
+	WAL bool
+	// How long to wait for another process holding a lock before failing
+	BusyTimeout time.Duration
+}
+
+// dsn returns the data source name go-sqlite3 opens the database with.
+func (o DatabaseOptions) dsn() string {
+	params := url.Values{}
+	if o.ReadOnly {
+		params.Set("mode", "ro")
+	} else {
+		params.Set("mode", "rwc")
+	}
+	// The journal mode is kept in the file, only a writer can change it
+	if o.WAL && !o.ReadOnly {
+		params.Set("_journal_mode", "WAL")
+	}
+	if o.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10))
+	}
+
+	if o.InMemory {
+		return ":memory:?" + params.Encode()
 	}
+	return "file:" + uriEscaper.Replace(o.Path) + "?" + params.Encode()
+}
 
-	conn.MustExec(schema)
+// uriEscaper escapes what would end the path of a file: URI.
+var uriEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
 
-	return &Database{
-		conn: conn,
-	}, nil
+// DatabaseMissingError is returned when a database opened read-only doesn't
+// exist, or holds no movies.
+type DatabaseMissingError struct {
+	Path  string
+	Empty bool
 }
 
-func (d *Database) Close() {
-	d.conn.Close()
+func (e *DatabaseMissingError) Error() string {
+	if e.Empty {
+		return fmt.Sprintf("database %s is empty, index movies or load a dump first", e.Path)
+	}
+	return fmt.Sprintf("database %s does not exist", e.Path)
 }
 
-func (d *Database) GetMoviesByPersonIDs(personIds []int, extraIds []int, popularity int) ([]Movie, error) {
-	query := `
-	SELECT DISTINCT c.job_type, m.id, m.title, m.language, m.popularity, m.runtime, m.release_date, m.adult,
-                    mi.path AS movie_image_path,
-                    p.id AS person_id, p.name AS person_name, pi.path AS person_image_path,
-    CASE
-        WHEN c.person_id IN (?, ?) THEN 1 ELSE 0
-    END AS is_in_list
-FROM
-    movies m
-    INNER JOIN credits c ON m.id = c.movie_id
-    LEFT JOIN movie_images mi ON m.id = mi.movie_id
-    INNER JOIN persons p ON p.id = c.person_id
-    LEFT JOIN person_images pi ON p.id = pi.person_id
-    WHERE p.id IN (?) OR p.popularity > ?
-ORDER BY
-    m.popularity DESC;
-    `
+// SchemaOutdatedError is returned for a database opened read-only that
+// needs migrating first.
+type SchemaOutdatedError struct {
+	Path    string
+	Version int
+	Latest  int
+}
 
-	// Create a query with the person IDs expanded
-	query, args, err := sqlx.In(query, personIds, extraIds, personIds, popularity)
+func (e *SchemaOutdatedError) Error() string {
+	return fmt.Sprintf("%s has schema version %d, but version %d is needed, migrate it first", e.Path, e.Version, e.Latest)
+}
+
+// NewDatabase opens the database, creating it if needed, and brings its
+// schema up to date. A read-only database is checked to be up to date and to
+// hold movies instead.
+func NewDatabase(opts DatabaseOptions) (*Database, error) {
+	db, err := OpenDatabase(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to construct query: %w", err)
+		return nil, err
 	}
 
-	// Rebind the query to match the SQLite driver
-	query = d.conn.Rebind(query)
+	if !opts.ReadOnly {
+		if _, err := db.Migrate(); err != nil {
+			db.Close()
+			return nil, err
+		}
+		// A database migrated by a binary with or without FTS5 is set up
+		// for this one
+		if err := db.updateSearchIndex(); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return db, nil
+	}
 
-	// Execute the query
-	rows, err := d.conn.Queryx(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+	if err := db.checkReadable(opts.Path); err != nil {
+		db.Close()
+		return nil, err
 	}
-	defer rows.Close()
 
-	// Prepare the result slice
-	var movies = map[int]*Movie{}
-	var movieIdsOrder = []int{}
+	return db, nil
+}
 
-	for rows.Next() {
-		var movie Movie
-		var movieImagePath sql.NullString
-		var person Person
-		var personImagePath sql.NullString
-		var personInList bool
-		var jobType JobType
-		var releaseDate string
-
-		// Scan the row into the structs
-		err := rows.Scan(
-			&jobType,
-			&movie.ID, &movie.Title, &movie.Language, &movie.Popularity, &movie.Runtime, &releaseDate, &movie.Adult,
-			&movieImagePath, &person.ID, &person.Name, &personImagePath,
-			&personInList,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
+func (d *Database) checkReadable(path string) error {
+	version, err := d.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return &DatabaseMissingError{Path: absPath(path), Empty: true}
+	}
+	if latest := LatestSchemaVersion(); version < latest {
+		return &SchemaOutdatedError{Path: path, Version: version, Latest: latest}
+	}
 
-		if _, exists := movies[movie.ID]; !exists {
-			movieIdsOrder = append(movieIdsOrder, movie.ID)
-			movies[movie.ID] = &movie
-		}
+	var movies int
+	if err := d.conn.Get(&movies, "SELECT COUNT(*) FROM movies"); err != nil {
+		return fmt.Errorf("failed to count movies: %w", err)
+	}
+	if movies == 0 {
+		return &DatabaseMissingError{Path: absPath(path), Empty: true}
+	}
 
-		layout := "2006-01-02 15:04:05-07:00"
-		movie.ReleaseDate, err = time.Parse(layout, releaseDate)
-		if err != nil {
-			return nil, err
-		}
+	return nil
+}
 
-		// Set the image paths if they are not null
-		if movieImagePath.Valid {
-			movie.Images = append(movie.Images, MovieImage{Path: movieImagePath.String})
-		}
-		if personImagePath.Valid {
-			person.Images = append(person.Images, PersonImage{Path: personImagePath.String})
+// OpenDatabase opens the database without migrating it. It refuses
+// databases written by a newer version, whose schema it doesn't know.
+func OpenDatabase(opts DatabaseOptions) (*Database, error) {
+	if opts.InMemory && opts.ReadOnly {
+		return nil, errors.New("an in-memory database can't be read-only")
+	}
+	// Opening a missing file read-only fails with an unhelpful "unable to
+	// open database file"
+	if opts.ReadOnly {
+		if _, err := os.Stat(opts.Path); errors.Is(err, os.ErrNotExist) {
+			return nil, &DatabaseMissingError{Path: absPath(opts.Path)}
 		}
+	}
 
-		existingMovie := movies[movie.ID]
-		existingMovie.Persons = append(existingMovie.Persons, MoviePerson{
-			Person:  person,
-			JobType: jobType,
-			InList:  personInList,
-		})
+	conn, err := sqlx.Connect("sqlite3", opts.dsn())
+	if err != nil {
+		return nil, err
+	}
+	// Every connection to :memory: gets a database of its own
+	if opts.InMemory {
+		conn.SetMaxOpenConns(1)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
+	db := &Database{
+		conn: conn,
 	}
 
-	result := make([]Movie, len(movieIdsOrder))
-	for i, id := range movieIdsOrder {
-		result[i] = *movies[id]
+	version, err := db.SchemaVersion()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if latest := LatestSchemaVersion(); version > latest {
+		db.Close()
+		return nil, &SchemaTooNewError{Path: opts.Path, Version: version, Latest: latest}
 	}
 
-	return result, nil
+	return db, nil
+}
+
+// absPath makes path absolute for error messages, as the database is looked
+// up relative to the working directory.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func (d *Database) Close() {
+	d.conn.Close()
+}
+
+// GetMoviesByPersonIDs returns the movies of the people in personIds, listing
+// them, the extraIds and everyone above popularity on the movies.
+func (d *Database) GetMoviesByPersonIDs(personIds []int, extraIds []int, popularity int) ([]Movie, error) {
+	return d.QueryMovies(MovieQuery{
+		PersonIDs:           personIds,
+		ExtraIDs:            extraIds,
+		MinPersonPopularity: float32(popularity),
+		IncludeAdult:        true,
+	})
 }
 
 func (d *Database) SetReferentialIntegrity(value bool) error {
@@ -261,14 +281,29 @@ func (d *Database) UpsertPerson(person Person, images []PersonImage) error {
 
 func (d *Database) UpsertMovie(movie Movie, images []MovieImage) error {
 	query := `
-    INSERT INTO movies (id, title, release_date, adult, popularity, runtime)
-    VALUES (:id, :title, :release_date, :adult, :popularity, :runtime)
+    INSERT INTO movies (
+        id, title, original_title, language, release_date, adult, popularity, runtime,
+        vote_count, vote_average, imdb_id, tagline, overview, budget, revenue
+    )
+    VALUES (
+        :id, :title, :original_title, :language, :release_date, :adult, :popularity, :runtime,
+        :vote_count, :vote_average, :imdb_id, :tagline, :overview, :budget, :revenue
+    )
     ON CONFLICT(id) DO UPDATE SET
         title = excluded.title,
+        original_title = excluded.original_title,
+        language = excluded.language,
         popularity = excluded.popularity,
         release_date = excluded.release_date,
         adult = excluded.adult,
-        runtime = excluded.runtime
+        runtime = excluded.runtime,
+        vote_count = excluded.vote_count,
+        vote_average = excluded.vote_average,
+        imdb_id = excluded.imdb_id,
+        tagline = excluded.tagline,
+        overview = excluded.overview,
+        budget = excluded.budget,
+        revenue = excluded.revenue
     `
 
 	_, err := d.conn.NamedExec(query, movie)
@@ -276,6 +311,10 @@ func (d *Database) UpsertMovie(movie Movie, images []MovieImage) error {
 		return fmt.Errorf("failed to upsert movie: %w", err)
 	}
 
+	if err := upsertMovieRelations(d.conn, movie); err != nil {
+		return err
+	}
+
 	for _, image := range images {
 		imgQuery := `
         INSERT INTO movie_images (movie_id, path)
@@ -300,9 +339,14 @@ func (d *Database) UpsertCredit(credit Credit) error {
 	}
 
 	query := `
-    INSERT INTO credits (person_id, movie_id, job_type)
-    VALUES (:person_id, :movie_id, :job_type)
-    ON CONFLICT(person_id, movie_id, job_type) DO NOTHING
+    INSERT INTO credits (person_id, movie_id, job_type, cast_order, character, credit_id, department, job)
+    VALUES (:person_id, :movie_id, :job_type, :cast_order, :character, :credit_id, :department, :job)
+    ON CONFLICT(person_id, movie_id, job_type) DO UPDATE SET
+        cast_order = excluded.cast_order,
+        character = excluded.character,
+        credit_id = excluded.credit_id,
+        department = excluded.department,
+        job = excluded.job
     `
 
 	_, err := d.conn.NamedExec(query, credit)
@@ -313,6 +357,219 @@ func (d *Database) UpsertCredit(credit Credit) error {
 	return nil
 }
 
+// upsertMovieRelations replaces the genres, collection, production companies
+// and countries of the movie with the ones on movie.
+func upsertMovieRelations(db sqlx.Execer, movie Movie) error {
+	if err := upsertMovieGenres(db, movie); err != nil {
+		return err
+	}
+	if err := upsertMovieCollection(db, movie); err != nil {
+		return err
+	}
+	if err := upsertMovieCompanies(db, movie); err != nil {
+		return err
+	}
+	return upsertMovieCountries(db, movie)
+}
+
+func upsertMovieCollection(db sqlx.Execer, movie Movie) error {
+	var collectionID *int
+	if movie.Collection != nil {
+		_, err := db.Exec(`
+        INSERT INTO collections (id, name, poster_path) VALUES (?, ?, ?)
+        ON CONFLICT(id) DO UPDATE SET name = excluded.name, poster_path = excluded.poster_path
+        `, movie.Collection.ID, movie.Collection.Name, movie.Collection.PosterPath)
+		if err != nil {
+			return fmt.Errorf("failed to upsert collection: %w", err)
+		}
+		collectionID = &movie.Collection.ID
+	}
+
+	if _, err := db.Exec("UPDATE movies SET collection_id = ? WHERE id = ?", collectionID, movie.ID); err != nil {
+		return fmt.Errorf("failed to set movie collection: %w", err)
+	}
+
+	return nil
+}
+
+func upsertMovieCompanies(db sqlx.Execer, movie Movie) error {
+	if _, err := db.Exec("DELETE FROM movie_companies WHERE movie_id = ?", movie.ID); err != nil {
+		return fmt.Errorf("failed to clear movie companies: %w", err)
+	}
+
+	for _, company := range movie.Companies {
+		_, err := db.Exec(`
+        INSERT INTO companies (id, name, origin_country) VALUES (?, ?, ?)
+        ON CONFLICT(id) DO UPDATE SET name = excluded.name, origin_country = excluded.origin_country
+        `, company.ID, company.Name, company.OriginCountry)
+		if err != nil {
+			return fmt.Errorf("failed to upsert company: %w", err)
+		}
+
+		_, err = db.Exec("INSERT OR IGNORE INTO movie_companies (movie_id, company_id) VALUES (?, ?)", movie.ID, company.ID)
+		if err != nil {
+			return fmt.Errorf("failed to upsert movie company: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func upsertMovieCountries(db sqlx.Execer, movie Movie) error {
+	if _, err := db.Exec("DELETE FROM movie_countries WHERE movie_id = ?", movie.ID); err != nil {
+		return fmt.Errorf("failed to clear movie countries: %w", err)
+	}
+
+	for _, country := range movie.Countries {
+		_, err := db.Exec(`
+        INSERT INTO countries (code, name) VALUES (?, ?)
+        ON CONFLICT(code) DO UPDATE SET name = excluded.name
+        `, country.Code, country.Name)
+		if err != nil {
+			return fmt.Errorf("failed to upsert country: %w", err)
+		}
+
+		_, err = db.Exec("INSERT OR IGNORE INTO movie_countries (movie_id, country_code) VALUES (?, ?)", movie.ID, country.Code)
+		if err != nil {
+			return fmt.Errorf("failed to upsert movie country: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// upsertMovieGenres replaces the genres of the movie with movie.Genres.
+func upsertMovieGenres(db sqlx.Execer, movie Movie) error {
+	if _, err := db.Exec("DELETE FROM movie_genres WHERE movie_id = ?", movie.ID); err != nil {
+		return fmt.Errorf("failed to clear movie genres: %w", err)
+	}
+
+	for _, genre := range movie.Genres {
+		_, err := db.Exec(`
+        INSERT INTO genres (id, name) VALUES (?, ?)
+        ON CONFLICT(id) DO UPDATE SET name = excluded.name
+        `, genre.ID, genre.Name)
+		if err != nil {
+			return fmt.Errorf("failed to upsert genre: %w", err)
+		}
+
+		_, err = db.Exec("INSERT OR IGNORE INTO movie_genres (movie_id, genre_id) VALUES (?, ?)", movie.ID, genre.ID)
+		if err != nil {
+			return fmt.Errorf("failed to upsert movie genre: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// movieGenres returns the genres of the movies, by movie ID.
+func (d *Database) movieGenres(movieIDs []int) (map[int][]Genre, error) {
+	genres := make(map[int][]Genre)
+	if len(movieIDs) == 0 {
+		return genres, nil
+	}
+
+	query, args, err := sqlx.In(`
+    SELECT mg.movie_id, g.id, g.name
+    FROM movie_genres mg
+        INNER JOIN genres g ON g.id = mg.genre_id
+    WHERE mg.movie_id IN (?)
+    ORDER BY mg.movie_id, g.name
+    `, movieIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct query: %w", err)
+	}
+
+	rows, err := d.conn.Queryx(d.conn.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie genres: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID int
+		var genre Genre
+		if err := rows.Scan(&movieID, &genre.ID, &genre.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan movie genre: %w", err)
+		}
+		genres[movieID] = append(genres[movieID], genre)
+	}
+
+	return genres, rows.Err()
+}
+
+// movieCompanies returns the production companies of the movies, by movie ID.
+func (d *Database) movieCompanies(movieIDs []int) (map[int][]Company, error) {
+	companies := make(map[int][]Company)
+	if len(movieIDs) == 0 {
+		return companies, nil
+	}
+
+	query, args, err := sqlx.In(`
+    SELECT mc.movie_id, c.id, c.name, COALESCE(c.origin_country, '')
+    FROM movie_companies mc
+        INNER JOIN companies c ON c.id = mc.company_id
+    WHERE mc.movie_id IN (?)
+    ORDER BY mc.movie_id, c.name
+    `, movieIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct query: %w", err)
+	}
+
+	rows, err := d.conn.Queryx(d.conn.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie companies: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID int
+		var company Company
+		if err := rows.Scan(&movieID, &company.ID, &company.Name, &company.OriginCountry); err != nil {
+			return nil, fmt.Errorf("failed to scan movie company: %w", err)
+		}
+		companies[movieID] = append(companies[movieID], company)
+	}
+
+	return companies, rows.Err()
+}
+
+// movieCountries returns the production countries of the movies, by movie ID.
+func (d *Database) movieCountries(movieIDs []int) (map[int][]Country, error) {
+	countries := make(map[int][]Country)
+	if len(movieIDs) == 0 {
+		return countries, nil
+	}
+
+	query, args, err := sqlx.In(`
+    SELECT mc.movie_id, c.code, c.name
+    FROM movie_countries mc
+        INNER JOIN countries c ON c.code = mc.country_code
+    WHERE mc.movie_id IN (?)
+    ORDER BY mc.movie_id, c.code
+    `, movieIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct query: %w", err)
+	}
+
+	rows, err := d.conn.Queryx(d.conn.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie countries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID int
+		var country Country
+		if err := rows.Scan(&movieID, &country.Code, &country.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan movie country: %w", err)
+		}
+		countries[movieID] = append(countries[movieID], country)
+	}
+
+	return countries, rows.Err()
+}
+
 func (d *Database) UpsertMovies(movies []Movie) error {
 	tx, err := d.conn.Beginx()
 	if err != nil {
@@ -320,16 +577,29 @@ func (d *Database) UpsertMovies(movies []Movie) error {
 	}
 
 	query := `
-    INSERT INTO movies (id, title, language, genres, release_date, adult, popularity, runtime)
-    VALUES (:id, :title, :language, :genres, :release_date, :adult, :popularity, :runtime)
+    INSERT INTO movies (
+        id, title, original_title, language, release_date, adult, popularity, runtime,
+        vote_count, vote_average, imdb_id, tagline, overview, budget, revenue
+    )
+    VALUES (
+        :id, :title, :original_title, :language, :release_date, :adult, :popularity, :runtime,
+        :vote_count, :vote_average, :imdb_id, :tagline, :overview, :budget, :revenue
+    )
     ON CONFLICT(id) DO UPDATE SET
         title = excluded.title,
-        genres = excluded.genres,
+        original_title = excluded.original_title,
         language = excluded.language,
         adult = excluded.adult,
         release_date = excluded.release_date,
         popularity = excluded.popularity,
-        runtime = excluded.runtime
+        runtime = excluded.runtime,
+        vote_count = excluded.vote_count,
+        vote_average = excluded.vote_average,
+        imdb_id = excluded.imdb_id,
+        tagline = excluded.tagline,
+        overview = excluded.overview,
+        budget = excluded.budget,
+        revenue = excluded.revenue
     `
 
 	stmt, err := tx.PrepareNamed(query)
@@ -345,6 +615,10 @@ func (d *Database) UpsertMovies(movies []Movie) error {
 			tx.Rollback()
 			return fmt.Errorf("failed to upsert movie: %w", err)
 		}
+		if err := upsertMovieRelations(tx, movie); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	for _, image := range images {
@@ -369,6 +643,12 @@ func (d *Database) UpsertMovies(movies []Movie) error {
 	return nil
 }
 
+// personRow is a person with their aliases as persons stores them.
+type personRow struct {
+	Person
+	AlsoKnownAs sql.NullString `db:"also_known_as"`
+}
+
 func (d *Database) UpsertPeople(people []Person) error {
 	tx, err := d.conn.Beginx()
 	if err != nil {
@@ -376,12 +656,13 @@ func (d *Database) UpsertPeople(people []Person) error {
 	}
 
 	query := `
-    INSERT INTO persons (id, birthday, known_for_department, name, gender, popularity, place_of_birth, profile_path, adult, imdb_id)
-    VALUES (:id, :birthday, :known_for_department, :name, :gender, :popularity, :place_of_birth, :profile_path, :adult, :imdb_id)
+    INSERT INTO persons (id, birthday, known_for_department, name, also_known_as, gender, popularity, place_of_birth, profile_path, adult, imdb_id)
+    VALUES (:id, :birthday, :known_for_department, :name, :also_known_as, :gender, :popularity, :place_of_birth, :profile_path, :adult, :imdb_id)
     ON CONFLICT(id) DO UPDATE SET
         birthday = excluded.birthday,
         known_for_department = excluded.known_for_department,
         name = excluded.name,
+        also_known_as = COALESCE(excluded.also_known_as, persons.also_known_as),
         gender = excluded.gender,
         popularity = excluded.popularity,
         place_of_birth = excluded.place_of_birth,
@@ -398,7 +679,17 @@ func (d *Database) UpsertPeople(people []Person) error {
 	images := []PersonImage{}
 	for _, person := range people {
 		images = append(images, person.Images...)
-		_, err := stmt.Exec(person)
+		row := personRow{Person: person}
+		// Unknown aliases are NULL, which keeps the stored ones
+		if person.AlsoKnownAs != nil {
+			akaBytes, err := json.Marshal(person.AlsoKnownAs)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to marshal AlsoKnownAs: %w", err)
+			}
+			row.AlsoKnownAs = sql.NullString{String: string(akaBytes), Valid: true}
+		}
+		_, err := stmt.Exec(row)
 		if err != nil {
 			tx.Rollback()
 			return fmt.Errorf("failed to upsert person: %w", err)
@@ -434,9 +725,14 @@ func (d *Database) UpsertCredits(credits []Credit) error {
 	}
 
 	query := `
-    INSERT INTO credits (person_id, movie_id, job_type)
-    VALUES (:person_id, :movie_id, :job_type)
-    ON CONFLICT(person_id, movie_id, job_type) DO NOTHING
+    INSERT INTO credits (person_id, movie_id, job_type, cast_order, character, credit_id, department, job)
+    VALUES (:person_id, :movie_id, :job_type, :cast_order, :character, :credit_id, :department, :job)
+    ON CONFLICT(person_id, movie_id, job_type) DO UPDATE SET
+        cast_order = excluded.cast_order,
+        character = excluded.character,
+        credit_id = excluded.credit_id,
+        department = excluded.department,
+        job = excluded.job
     `
 
 	stmt, err := tx.PrepareNamed(query)
@@ -458,3 +754,200 @@ func (d *Database) UpsertCredits(credits []Credit) error {
 	}
 	return nil
 }
+
+type CrawlStatus string
+
+const (
+	CrawlStatusIndexed CrawlStatus = "indexed"
+	CrawlStatusSkipped CrawlStatus = "skipped"
+)
+
+// GetCrawledMovieIDs returns the movies the indexer has already processed,
+// both the ones it stored and the ones it skipped.
+func (d *Database) GetCrawledMovieIDs() (map[int]CrawlStatus, error) {
+	rows, err := d.conn.Queryx("SELECT movie_id, status FROM crawl_state")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl state: %w", err)
+	}
+	defer rows.Close()
+
+	crawled := make(map[int]CrawlStatus)
+	for rows.Next() {
+		var id int
+		var status CrawlStatus
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl state: %w", err)
+		}
+		crawled[id] = status
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return crawled, nil
+}
+
+func (d *Database) MarkMoviesCrawled(statuses map[int]CrawlStatus) error {
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+    INSERT INTO crawl_state (movie_id, status, crawled_at)
+    VALUES (?, ?, ?)
+    ON CONFLICT(movie_id) DO UPDATE SET
+        status = excluded.status,
+        crawled_at = excluded.crawled_at
+    `)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for id, status := range statuses {
+		if _, err := stmt.Exec(id, status, now); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to mark movie %d as crawled: %w", id, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ResetCrawlState forgets which movies have been crawled, so the next crawl
+// starts from the beginning. The crawled data itself is kept.
+func (d *Database) ResetCrawlState() error {
+	if _, err := d.conn.Exec("DELETE FROM crawl_state"); err != nil {
+		return fmt.Errorf("failed to reset crawl state: %w", err)
+	}
+	return nil
+}
+
+const (
+	MetadataMovieExportDate  = "movie_export_date"
+	MetadataMovieExportFile  = "movie_export_file"
+	MetadataPersonExportDate = "person_export_date"
+	MetadataPersonExportFile = "person_export_file"
+)
+
+// SetExportMetadata records the file name and date of the export the data
+// came from. A date that can't be told from the file name is removed.
+func (d *Database) SetExportMetadata(export ExportFile) error {
+	fileKey, dateKey := MetadataMovieExportFile, MetadataMovieExportDate
+	if export.Kind == PersonExport {
+		fileKey, dateKey = MetadataPersonExportFile, MetadataPersonExportDate
+	}
+
+	if err := d.SetMetadata(fileKey, filepath.Base(export.Path)); err != nil {
+		return err
+	}
+	if export.Date.IsZero() {
+		if _, err := d.conn.Exec("DELETE FROM metadata WHERE key = ?", dateKey); err != nil {
+			return fmt.Errorf("failed to delete metadata %s: %w", dateKey, err)
+		}
+		return nil
+	}
+	return d.SetMetadata(dateKey, export.Date.Format(time.DateOnly))
+}
+
+func (d *Database) SetMetadata(key string, value string) error {
+	_, err := d.conn.Exec(`
+    INSERT INTO metadata (key, value)
+    VALUES (?, ?)
+    ON CONFLICT(key) DO UPDATE SET
+        value = excluded.value
+    `, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to set metadata %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// GetMetadata returns the value stored under key, and false if there is none.
+func (d *Database) GetMetadata(key string) (string, bool, error) {
+	var value string
+	err := d.conn.Get(&value, "SELECT value FROM metadata WHERE key = ?", key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get metadata %s: %w", key, err)
+	}
+
+	return value, true, nil
+}
+
+var tables = []string{
+	"persons", "movies", "credits", "person_images", "movie_images", "genres", "movie_genres",
+	"collections", "companies", "movie_companies", "countries", "movie_countries", "crawl_state",
+}
+
+type TableCount struct {
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+}
+
+func (d *Database) TableCounts() ([]TableCount, error) {
+	counts := make([]TableCount, 0, len(tables))
+	for _, table := range tables {
+		var rows int
+		if err := d.conn.Get(&rows, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		counts = append(counts, TableCount{Table: table, Rows: rows})
+	}
+
+	return counts, nil
+}
+
+// GetPeople returns the name, popularity, department and also-known-as
+// names of every person in the database.
+func (d *Database) GetPeople() ([]Person, error) {
+	return d.queryPeople("SELECT id, name, popularity, known_for_department, also_known_as FROM persons")
+}
+
+// queryPeople runs a query selecting the id, name, popularity,
+// known_for_department and also_known_as of people.
+func (d *Database) queryPeople(query string, args ...any) ([]Person, error) {
+	rows, err := d.conn.Queryx(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query people: %w", err)
+	}
+	defer rows.Close()
+
+	var people []Person
+	for rows.Next() {
+		var person Person
+		var name, department, alsoKnownAs sql.NullString
+		var popularity sql.NullFloat64
+
+		if err := rows.Scan(&person.ID, &name, &popularity, &department, &alsoKnownAs); err != nil {
+			return nil, fmt.Errorf("failed to scan person: %w", err)
+		}
+
+		person.Name = name.String
+		person.Popularity = float32(popularity.Float64)
+		person.KnownForDepartment = department.String
+		if alsoKnownAs.Valid && alsoKnownAs.String != "" {
+			if err := json.Unmarshal([]byte(alsoKnownAs.String), &person.AlsoKnownAs); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal also_known_as of person %d: %w", person.ID, err)
+			}
+		}
+
+		people = append(people, person)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return people, nil
+}
@@ -1,7 +1,11 @@
 package tmdbankigenerator
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -14,9 +18,10 @@ func TestDatabase(t *testing.T) {
 
 	_, _ = conn.Exec("PRAGMA foreign_keys = ON;")
 
-	conn.MustExec(schema)
-
 	db := &Database{conn: conn}
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate DB: %v", err)
+	}
 
 	t.Run("UpsertPerson", func(t *testing.T) {
 		person := Person{
@@ -63,8 +68,9 @@ func TestDatabase(t *testing.T) {
 
 	t.Run("UpsertMovie", func(t *testing.T) {
 		movie := Movie{
-			ID:    456,
-			Title: "Movie Title",
+			ID:       456,
+			Title:    "Movie Title",
+			Language: LanguageEnglish,
 		}
 		if err := db.UpsertMovie(movie, nil); err != nil {
 			t.Errorf("UpsertMovie failed: %v", err)
@@ -80,17 +86,24 @@ func TestDatabase(t *testing.T) {
 		}
 
 		movie.Title = "Updated Movie Title"
+		movie.Language = LanguageKorean
 		if err := db.UpsertMovie(movie, nil); err != nil {
 			t.Errorf("UpsertMovie (update) failed: %v", err)
 		}
 
-		var updatedTitle string
-		err = conn.Get(&updatedTitle, "SELECT title FROM movies WHERE id = ?", movie.ID)
+		var updated struct {
+			Title    string
+			Language string
+		}
+		err = conn.Get(&updated, "SELECT title, language FROM movies WHERE id = ?", movie.ID)
 		if err != nil {
-			t.Fatalf("failed to select updated title: %v", err)
+			t.Fatalf("failed to select updated movie: %v", err)
 		}
-		if updatedTitle != "Updated Movie Title" {
-			t.Errorf("expected updated title to be %q, got %q", movie.Title, updatedTitle)
+		if updated.Title != "Updated Movie Title" {
+			t.Errorf("expected updated title to be %q, got %q", movie.Title, updated.Title)
+		}
+		if updated.Language != LanguageKorean {
+			t.Errorf("expected updated language to be %q, got %q", LanguageKorean, updated.Language)
 		}
 	})
 
@@ -133,5 +146,183 @@ func TestDatabase(t *testing.T) {
 		if count != 1 {
 			t.Errorf("expected count to remain 1, got %d", count)
 		}
+
+		credit.CastOrder = 3
+		credit.Character = "Himself"
+		credit.CreditID = "52fe4250c3a36847f80149f3"
+		if err := db.UpsertCredits([]Credit{credit}); err != nil {
+			t.Errorf("UpsertCredits failed: %v", err)
+		}
+
+		var stored Credit
+		err = conn.Get(&stored, "SELECT person_id, movie_id, job_type, cast_order, character, credit_id, department, job FROM credits WHERE person_id = ? AND movie_id = ?", person.ID, movie.ID)
+		if err != nil {
+			t.Fatalf("failed to query credits table after update: %v", err)
+		}
+		if stored.CastOrder != 3 || stored.Character != "Himself" || stored.CreditID != credit.CreditID {
+			t.Errorf("expected the credit details to be updated, got %+v", stored)
+		}
+	})
+
+	t.Run("Genres", func(t *testing.T) {
+		drama, _ := LookupGenre("Drama")
+		comedy, _ := LookupGenre("Comedy")
+
+		movie := Movie{ID: 777, Title: "Genre Movie", Genres: []Genre{drama, comedy}}
+		if err := db.UpsertMovies([]Movie{movie}); err != nil {
+			t.Fatalf("UpsertMovies failed: %v", err)
+		}
+
+		genres, err := db.movieGenres([]int{movie.ID})
+		if err != nil {
+			t.Fatalf("movieGenres failed: %v", err)
+		}
+		if got := GenreNames(genres[movie.ID]); len(got) != 2 || got[0] != "Comedy" || got[1] != "Drama" {
+			t.Errorf("expected Comedy and Drama, got %v", got)
+		}
+
+		movie.Genres = []Genre{drama}
+		if err := db.UpsertMovies([]Movie{movie}); err != nil {
+			t.Fatalf("UpsertMovies (update) failed: %v", err)
+		}
+
+		genres, err = db.movieGenres([]int{movie.ID})
+		if err != nil {
+			t.Fatalf("movieGenres failed: %v", err)
+		}
+		if got := GenreNames(genres[movie.ID]); len(got) != 1 || got[0] != "Drama" {
+			t.Errorf("expected the genres to be replaced by Drama, got %v", got)
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		if _, ok, err := db.GetMetadata("missing"); err != nil || ok {
+			t.Errorf("expected no value for missing key, got ok=%v err=%v", ok, err)
+		}
+
+		for _, value := range []string{"2025-01-10", "2025-01-11"} {
+			if err := db.SetMetadata(MetadataMovieExportDate, value); err != nil {
+				t.Fatalf("SetMetadata failed: %v", err)
+			}
+
+			got, ok, err := db.GetMetadata(MetadataMovieExportDate)
+			if err != nil || !ok {
+				t.Fatalf("GetMetadata failed: ok=%v err=%v", ok, err)
+			}
+			if got != value {
+				t.Errorf("expected %q, got %q", value, got)
+			}
+		}
+		export := ExportFile{Kind: PersonExport, Path: "exports/person_ids_01_11_2025.json.gz", Date: time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC)}
+		if err := db.SetExportMetadata(export); err != nil {
+			t.Fatalf("SetExportMetadata failed: %v", err)
+		}
+		file, _, _ := db.GetMetadata(MetadataPersonExportFile)
+		date, _, _ := db.GetMetadata(MetadataPersonExportDate)
+		if file != "person_ids_01_11_2025.json.gz" || date != "2025-01-11" {
+			t.Errorf("expected the person export to be recorded, got %q from %q", file, date)
+		}
+
+		// A file without a date in its name doesn't keep the old date
+		if err := db.SetExportMetadata(ExportFile{Kind: PersonExport, Path: "people.json"}); err != nil {
+			t.Fatalf("SetExportMetadata failed: %v", err)
+		}
+		if _, ok, err := db.GetMetadata(MetadataPersonExportDate); err != nil || ok {
+			t.Errorf("expected the person export date to be removed, got ok=%v err=%v", ok, err)
+		}
+		if date, _, _ := db.GetMetadata(MetadataMovieExportDate); date != "2025-01-11" {
+			t.Errorf("expected the movie export date to be kept, got %q", date)
+		}
+	})
+
+	t.Run("CrawlState", func(t *testing.T) {
+		if err := db.ResetCrawlState(); err != nil {
+			t.Fatalf("ResetCrawlState failed: %v", err)
+		}
+
+		statuses := map[int]CrawlStatus{1: CrawlStatusIndexed, 2: CrawlStatusSkipped}
+		if err := db.MarkMoviesCrawled(statuses); err != nil {
+			t.Fatalf("MarkMoviesCrawled failed: %v", err)
+		}
+
+		crawled, err := db.GetCrawledMovieIDs()
+		if err != nil {
+			t.Fatalf("GetCrawledMovieIDs failed: %v", err)
+		}
+		if len(crawled) != 2 || crawled[1] != CrawlStatusIndexed || crawled[2] != CrawlStatusSkipped {
+			t.Errorf("expected %v, got %v", statuses, crawled)
+		}
+
+		if err := db.ResetCrawlState(); err != nil {
+			t.Fatalf("ResetCrawlState failed: %v", err)
+		}
+		crawled, err = db.GetCrawledMovieIDs()
+		if err != nil {
+			t.Fatalf("GetCrawledMovieIDs failed: %v", err)
+		}
+		if len(crawled) != 0 {
+			t.Errorf("expected no crawled movies after reset, got %v", crawled)
+		}
 	})
 }
+
+func TestReadOnlyDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "read-only.db")
+
+	var missing *DatabaseMissingError
+	if _, err := NewDatabase(DatabaseOptions{Path: path, ReadOnly: true}); !errors.As(err, &missing) || missing.Empty {
+		t.Fatalf("expected a missing database error, got %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected opening read-only not to create the database")
+	}
+
+	// Outdated
+	old, err := OpenDatabase(DatabaseOptions{Path: path})
+	if err != nil {
+		t.Fatalf("OpenDatabase failed: %v", err)
+	}
+	if err := old.applyMigration(migrations[0]); err != nil {
+		t.Fatalf("failed to set up an outdated database: %v", err)
+	}
+	old.Close()
+
+	var outdated *SchemaOutdatedError
+	if _, err := NewDatabase(DatabaseOptions{Path: path, ReadOnly: true}); !errors.As(err, &outdated) || outdated.Version != 1 {
+		t.Fatalf("expected an outdated schema error, got %v", err)
+	}
+
+	db, err := NewDatabase(DatabaseOptions{Path: path, WAL: true, BusyTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := NewDatabase(DatabaseOptions{Path: path, ReadOnly: true}); !errors.As(err, &missing) || !missing.Empty {
+		t.Fatalf("expected an empty database error, got %v", err)
+	}
+
+	if err := db.UpsertMovies([]Movie{{ID: 1, Title: "Only"}}); err != nil {
+		t.Fatalf("UpsertMovies failed: %v", err)
+	}
+
+	readOnly, err := NewDatabase(DatabaseOptions{Path: path, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer readOnly.Close()
+
+	if results, err := readOnly.SearchMovies("only", 0); errors.Is(err, ErrSearchUnavailable) {
+		// Built without FTS5
+	} else if err != nil || len(results) != 1 {
+		t.Errorf("expected to read the movie, got %v, %v", results, err)
+	}
+	if err := readOnly.UpsertMovies([]Movie{{ID: 2, Title: "Other"}}); err == nil {
+		t.Errorf("expected writing to a read-only database to fail")
+	}
+
+	if _, err := NewDatabase(DatabaseOptions{InMemory: true, ReadOnly: true}); err == nil {
+		t.Errorf("expected a read-only in-memory database to be refused")
+	}
+}
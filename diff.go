@@ -0,0 +1,288 @@
+package tmdbankigenerator
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// DiffOptions tune what DiffDatabases reports.
+type DiffOptions struct {
+	// Popularity changes smaller than this are left out
+	MinPopularityChange float32
+}
+
+// DatasetDiff is what changed between two databases, e.g. two indexing runs
+// on different TMDB exports.
+type DatasetDiff struct {
+	AddedMovies       []Movie
+	RemovedMovies     []Movie
+	RenamedMovies     []MovieRename
+	AddedPeople       []Person
+	RemovedPeople     []Person
+	AddedCredits      []CreditChange
+	RemovedCredits    []CreditChange
+	PopularityChanges []PopularityChange
+}
+
+type MovieRename struct {
+	ID       int
+	OldTitle string
+	NewTitle string
+}
+
+// CreditChange is an added or removed credit, with the names to show it by.
+type CreditChange struct {
+	Credit
+	PersonName string
+	MovieTitle string
+}
+
+type DiffKind string
+
+const (
+	DiffKindMovie  DiffKind = "movie"
+	DiffKindPerson DiffKind = "person"
+)
+
+type PopularityChange struct {
+	Kind DiffKind
+	ID   int
+	Name string
+	Old  float32
+	New  float32
+}
+
+func (c PopularityChange) Delta() float32 {
+	return c.New - c.Old
+}
+
+// Empty reports whether nothing changed.
+func (d *DatasetDiff) Empty() bool {
+	return len(d.AddedMovies) == 0 && len(d.RemovedMovies) == 0 && len(d.RenamedMovies) == 0 &&
+		len(d.AddedPeople) == 0 && len(d.RemovedPeople) == 0 &&
+		len(d.AddedCredits) == 0 && len(d.RemovedCredits) == 0 && len(d.PopularityChanges) == 0
+}
+
+// ChangedMovieIDs returns the movies that were added, removed or renamed, or
+// gained or lost credits, or whose popularity moved.
+func (d *DatasetDiff) ChangedMovieIDs() map[int]bool {
+	ids := make(map[int]bool)
+	for _, movies := range [][]Movie{d.AddedMovies, d.RemovedMovies} {
+		for _, movie := range movies {
+			ids[movie.ID] = true
+		}
+	}
+	for _, rename := range d.RenamedMovies {
+		ids[rename.ID] = true
+	}
+	for _, credits := range [][]CreditChange{d.AddedCredits, d.RemovedCredits} {
+		for _, credit := range credits {
+			ids[credit.MovieID] = true
+		}
+	}
+	for _, change := range d.PopularityChanges {
+		if change.Kind == DiffKindMovie {
+			ids[change.ID] = true
+		}
+	}
+	return ids
+}
+
+// DiffDatabases compares the movies, people and credits of old and new.
+func DiffDatabases(old, new *Database, opts DiffOptions) (*DatasetDiff, error) {
+	oldMovies, err := old.movieSummaries()
+	if err != nil {
+		return nil, err
+	}
+	newMovies, err := new.movieSummaries()
+	if err != nil {
+		return nil, err
+	}
+	oldPeople, err := old.personSummaries()
+	if err != nil {
+		return nil, err
+	}
+	newPeople, err := new.personSummaries()
+	if err != nil {
+		return nil, err
+	}
+	oldCredits, err := old.creditKeys()
+	if err != nil {
+		return nil, err
+	}
+	newCredits, err := new.creditKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &DatasetDiff{}
+	bigMove := func(old, new float32) bool {
+		delta := new - old
+		return delta >= opts.MinPopularityChange || -delta >= opts.MinPopularityChange
+	}
+
+	for id, movie := range newMovies {
+		oldMovie, ok := oldMovies[id]
+		if !ok {
+			diff.AddedMovies = append(diff.AddedMovies, movie)
+			continue
+		}
+		if oldMovie.Title != movie.Title {
+			diff.RenamedMovies = append(diff.RenamedMovies, MovieRename{ID: id, OldTitle: oldMovie.Title, NewTitle: movie.Title})
+		}
+		if oldMovie.Popularity != movie.Popularity && bigMove(oldMovie.Popularity, movie.Popularity) {
+			diff.PopularityChanges = append(diff.PopularityChanges, PopularityChange{
+				Kind: DiffKindMovie, ID: id, Name: movie.Title, Old: oldMovie.Popularity, New: movie.Popularity,
+			})
+		}
+	}
+	for id, movie := range oldMovies {
+		if _, ok := newMovies[id]; !ok {
+			diff.RemovedMovies = append(diff.RemovedMovies, movie)
+		}
+	}
+
+	for id, person := range newPeople {
+		oldPerson, ok := oldPeople[id]
+		if !ok {
+			diff.AddedPeople = append(diff.AddedPeople, person)
+			continue
+		}
+		if oldPerson.Popularity != person.Popularity && bigMove(oldPerson.Popularity, person.Popularity) {
+			diff.PopularityChanges = append(diff.PopularityChanges, PopularityChange{
+				Kind: DiffKindPerson, ID: id, Name: person.Name, Old: oldPerson.Popularity, New: person.Popularity,
+			})
+		}
+	}
+	for id, person := range oldPeople {
+		if _, ok := newPeople[id]; !ok {
+			diff.RemovedPeople = append(diff.RemovedPeople, person)
+		}
+	}
+
+	creditChange := func(key creditKey, movies map[int]Movie, people map[int]Person) CreditChange {
+		return CreditChange{
+			Credit:     Credit{PersonID: key.PersonID, MovieID: key.MovieID, JobType: key.JobType},
+			PersonName: people[key.PersonID].Name,
+			MovieTitle: movies[key.MovieID].Title,
+		}
+	}
+	for key := range newCredits {
+		if !oldCredits[key] {
+			diff.AddedCredits = append(diff.AddedCredits, creditChange(key, newMovies, newPeople))
+		}
+	}
+	for key := range oldCredits {
+		if !newCredits[key] {
+			diff.RemovedCredits = append(diff.RemovedCredits, creditChange(key, oldMovies, oldPeople))
+		}
+	}
+
+	diff.sort()
+
+	return diff, nil
+}
+
+func (d *DatasetDiff) sort() {
+	byPopularity := func(a, b Movie) int {
+		return cmp.Or(cmp.Compare(b.Popularity, a.Popularity), cmp.Compare(a.ID, b.ID))
+	}
+	slices.SortFunc(d.AddedMovies, byPopularity)
+	slices.SortFunc(d.RemovedMovies, byPopularity)
+
+	peopleByPopularity := func(a, b Person) int {
+		return cmp.Or(cmp.Compare(b.Popularity, a.Popularity), cmp.Compare(a.ID, b.ID))
+	}
+	slices.SortFunc(d.AddedPeople, peopleByPopularity)
+	slices.SortFunc(d.RemovedPeople, peopleByPopularity)
+
+	slices.SortFunc(d.RenamedMovies, func(a, b MovieRename) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	byMovie := func(a, b CreditChange) int {
+		return cmp.Or(
+			cmp.Compare(a.MovieTitle, b.MovieTitle),
+			cmp.Compare(a.MovieID, b.MovieID),
+			cmp.Compare(a.PersonName, b.PersonName),
+			cmp.Compare(a.PersonID, b.PersonID),
+			cmp.Compare(a.JobType, b.JobType),
+		)
+	}
+	slices.SortFunc(d.AddedCredits, byMovie)
+	slices.SortFunc(d.RemovedCredits, byMovie)
+
+	abs := func(f float32) float32 { return max(f, -f) }
+	slices.SortFunc(d.PopularityChanges, func(a, b PopularityChange) int {
+		return cmp.Or(
+			cmp.Compare(abs(b.Delta()), abs(a.Delta())),
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+}
+
+type creditKey struct {
+	PersonID int
+	MovieID  int
+	JobType  JobType
+}
+
+// movieSummaries returns the ID, title and popularity of every movie.
+func (d *Database) movieSummaries() (map[int]Movie, error) {
+	var movies []Movie
+	err := d.conn.Select(&movies, "SELECT id, COALESCE(title, '') AS title, COALESCE(popularity, 0) AS popularity FROM movies")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies: %w", err)
+	}
+
+	byID := make(map[int]Movie, len(movies))
+	for _, movie := range movies {
+		byID[movie.ID] = movie
+	}
+	return byID, nil
+}
+
+// personSummaries returns the ID, name and popularity of every person.
+func (d *Database) personSummaries() (map[int]Person, error) {
+	var people []Person
+	err := d.conn.Select(&people, "SELECT id, COALESCE(name, '') AS name, COALESCE(popularity, 0) AS popularity FROM persons")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query people: %w", err)
+	}
+
+	byID := make(map[int]Person, len(people))
+	for _, person := range people {
+		byID[person.ID] = person
+	}
+	return byID, nil
+}
+
+func (d *Database) creditKeys() (map[creditKey]bool, error) {
+	rows, err := d.conn.Query("SELECT person_id, movie_id, job_type FROM credits")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query credits: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make(map[creditKey]bool)
+	for rows.Next() {
+		var key creditKey
+		if err := rows.Scan(&key.PersonID, &key.MovieID, &key.JobType); err != nil {
+			return nil, fmt.Errorf("failed to scan credit: %w", err)
+		}
+		keys[key] = true
+	}
+
+	return keys, rows.Err()
+}
+
+// Snapshot writes a compacted copy of the database to path, to diff later
+// databases against. path must not exist yet.
+func (d *Database) Snapshot(path string) error {
+	if _, err := d.conn.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to write snapshot to %s: %w", path, err)
+	}
+	return nil
+}
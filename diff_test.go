@@ -0,0 +1,100 @@
+package tmdbankigenerator
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffDatabases(t *testing.T) {
+	db := newTestQueryDatabase(t)
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := db.Snapshot(snapshotPath); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if err := db.Snapshot(snapshotPath); err == nil {
+		t.Errorf("expected a snapshot over an existing file to fail")
+	}
+
+	old, err := NewDatabase(DatabaseOptions{Path: snapshotPath})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	t.Cleanup(old.Close)
+
+	t.Run("Unchanged", func(t *testing.T) {
+		diff, err := DiffDatabases(old, db, DiffOptions{})
+		if err != nil {
+			t.Fatalf("DiffDatabases failed: %v", err)
+		}
+		if !diff.Empty() {
+			t.Errorf("expected a snapshot to equal its database, got %+v", diff)
+		}
+	})
+
+	if err := db.UpsertMovies([]Movie{
+		{ID: 1, Title: "Old English Redux", Language: LanguageEnglish, Popularity: 11},
+		{ID: 2, Title: "New Danish", Language: LanguageDanish, Popularity: 80},
+		{ID: 5, Title: "Sequel", Language: LanguageEnglish, Popularity: 15},
+	}); err != nil {
+		t.Fatalf("UpsertMovies failed: %v", err)
+	}
+	if err := db.UpsertPeople([]Person{{ID: 14, Name: "Newcomer", Popularity: 3}}); err != nil {
+		t.Fatalf("UpsertPeople failed: %v", err)
+	}
+	if err := db.UpsertCredits([]Credit{{PersonID: 14, MovieID: 5, JobType: JobTypeCast}}); err != nil {
+		t.Fatalf("UpsertCredits failed: %v", err)
+	}
+	for _, query := range []string{
+		"DELETE FROM credits WHERE person_id = 13",
+		"DELETE FROM persons WHERE id = 13",
+		"DELETE FROM credits WHERE movie_id = 4",
+		"DELETE FROM movies WHERE id = 4",
+	} {
+		if _, err := db.conn.Exec(query); err != nil {
+			t.Fatalf("%s failed: %v", query, err)
+		}
+	}
+
+	diff, err := DiffDatabases(old, db, DiffOptions{MinPopularityChange: 10})
+	if err != nil {
+		t.Fatalf("DiffDatabases failed: %v", err)
+	}
+
+	if len(diff.AddedMovies) != 1 || diff.AddedMovies[0].ID != 5 {
+		t.Errorf("expected Sequel to be added, got %+v", diff.AddedMovies)
+	}
+	if len(diff.RemovedMovies) != 1 || diff.RemovedMovies[0].ID != 4 {
+		t.Errorf("expected Without Lead to be removed, got %+v", diff.RemovedMovies)
+	}
+	if len(diff.RenamedMovies) != 1 || diff.RenamedMovies[0] != (MovieRename{ID: 1, OldTitle: "Old English", NewTitle: "Old English Redux"}) {
+		t.Errorf("expected Old English to be renamed, got %+v", diff.RenamedMovies)
+	}
+	if len(diff.AddedPeople) != 1 || diff.AddedPeople[0].Name != "Newcomer" {
+		t.Errorf("expected Newcomer to be added, got %+v", diff.AddedPeople)
+	}
+	if len(diff.RemovedPeople) != 1 || diff.RemovedPeople[0].Name != "Nobody" {
+		t.Errorf("expected Nobody to be removed, got %+v", diff.RemovedPeople)
+	}
+	if len(diff.AddedCredits) != 1 || diff.AddedCredits[0].PersonName != "Newcomer" || diff.AddedCredits[0].MovieTitle != "Sequel" {
+		t.Errorf("expected Newcomer's credit on Sequel to be added, got %+v", diff.AddedCredits)
+	}
+	// Removed credits are named after the old database
+	if len(diff.RemovedCredits) != 2 || diff.RemovedCredits[0].MovieTitle != "Old English" || diff.RemovedCredits[1].MovieTitle != "Without Lead" {
+		t.Errorf("expected the credits of Nobody and on Without Lead to be removed, got %+v", diff.RemovedCredits)
+	}
+	// Old English only moved by 1
+	if len(diff.PopularityChanges) != 1 || diff.PopularityChanges[0].ID != 2 || diff.PopularityChanges[0].Delta() != 50 {
+		t.Errorf("expected New Danish to move by 50, got %+v", diff.PopularityChanges)
+	}
+
+	changed := diff.ChangedMovieIDs()
+	for _, id := range []int{1, 2, 4, 5} {
+		if !changed[id] {
+			t.Errorf("expected movie %d to be changed, got %v", id, changed)
+		}
+	}
+	if changed[3] {
+		t.Errorf("expected movie 3 to be unchanged")
+	}
+}
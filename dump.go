@@ -0,0 +1,385 @@
+package tmdbankigenerator
+
+import (
+	"cmp"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// dumpTables are the tables a dump holds, parents before the tables
+// referencing them. The search index is rebuilt from persons and movies.
+var dumpTables = []string{
+	"genres", "collections", "companies", "countries",
+	"persons", "movies", "credits", "person_images", "movie_images",
+	"movie_genres", "movie_companies", "movie_countries",
+	"crawl_state", "metadata",
+}
+
+const DumpManifestFile = "manifest.json"
+
+// DumpManifest describes a dump, it's written next to the table files.
+type DumpManifest struct {
+	SchemaVersion int            `json:"schema_version"`
+	Tables        map[string]int `json:"tables"`
+}
+
+type DumpOptions struct {
+	// Gzip the table files
+	Gzip bool
+}
+
+// dumpFile returns the file a table is dumped to.
+func dumpFile(dir, table string, gzipped bool) string {
+	if gzipped {
+		return filepath.Join(dir, table+".ndjson.gz")
+	}
+	return filepath.Join(dir, table+".ndjson")
+}
+
+// Dump writes every table of the dataset to dir, one NDJSON file per table
+// with one row per line, sorted by primary key. Columns are sorted by name
+// and NULLs left out, so the same data always dumps to the same files and
+// changes to it diff well.
+func (d *Database) Dump(dir string, opts DumpOptions) (*DumpManifest, error) {
+	version, err := d.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	manifest := &DumpManifest{SchemaVersion: version, Tables: make(map[string]int, len(dumpTables))}
+	for _, table := range dumpTables {
+		rows, err := d.dumpTable(table, dumpFile(dir, table, opts.Gzip), opts.Gzip)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Tables[table] = rows
+
+		// A dump from before switching gzip on or off would be loaded
+		// instead
+		if err := os.Remove(dumpFile(dir, table, !opts.Gzip)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, DumpManifestFile), append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func (d *Database) dumpTable(table, path string, gzipped bool) (count int, err error) {
+	primaryKey, _, err := tableColumns(d.conn, table)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	var w io.Writer = f
+	if gzipped {
+		gz := gzip.NewWriter(f)
+		defer func() {
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = gz
+	}
+
+	rows, err := d.conn.Queryx(fmt.Sprintf("SELECT * FROM %s ORDER BY %s", table, strings.Join(primaryKey, ", ")))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for rows.Next() {
+		row := make(map[string]any)
+		if err := rows.MapScan(row); err != nil {
+			return 0, fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		for column, value := range row {
+			switch value := value.(type) {
+			case nil:
+				delete(row, column)
+			case []byte:
+				row[column] = string(value)
+			}
+		}
+
+		if err := encoder.Encode(row); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", table, err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return count, nil
+}
+
+type tableColumn struct {
+	Name string `db:"name"`
+	// Position in the primary key from 1, 0 for other columns
+	PK int `db:"pk"`
+}
+
+// tableColumns returns the primary key columns of table in key order, and
+// every column.
+func tableColumns(q sqlx.Queryer, table string) ([]string, map[string]bool, error) {
+	var info []tableColumn
+	if err := sqlx.Select(q, &info, "SELECT name, pk FROM pragma_table_info(?)", table); err != nil {
+		return nil, nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	slices.SortFunc(info, func(a, b tableColumn) int {
+		return cmp.Compare(a.PK, b.PK)
+	})
+
+	columns := make(map[string]bool, len(info))
+	var primaryKey []string
+	for _, column := range info {
+		columns[column.Name] = true
+		if column.PK > 0 {
+			primaryKey = append(primaryKey, column.Name)
+		}
+	}
+	if len(primaryKey) == 0 {
+		primaryKey = []string{"rowid"}
+	}
+
+	return primaryKey, columns, nil
+}
+
+// ReadDumpManifest reads the manifest of the dump in dir.
+func ReadDumpManifest(dir string) (*DumpManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, DumpManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump manifest: %w", err)
+	}
+
+	var manifest DumpManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DumpManifestFile, err)
+	}
+
+	return &manifest, nil
+}
+
+// Load fills the database with the dump in dir, in one transaction. The
+// database must be empty. Dumps of an older schema load fine, the columns
+// they miss are left NULL.
+func (d *Database) Load(dir string) (*DumpManifest, error) {
+	manifest, err := ReadDumpManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if latest := LatestSchemaVersion(); manifest.SchemaVersion > latest {
+		return nil, &SchemaTooNewError{Path: dir, Version: manifest.SchemaVersion, Latest: latest}
+	}
+
+	for _, table := range dumpTables {
+		// Genres are filled in by the migrations
+		if table == "genres" {
+			continue
+		}
+		var rows int
+		if err := d.conn.Get(&rows, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		if rows > 0 {
+			return nil, fmt.Errorf("can only load into an empty database, %s has %d rows", table, rows)
+		}
+	}
+
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range dumpTables {
+		rows, err := loadTable(tx, dir, table)
+		if err != nil {
+			return nil, err
+		}
+		if want, ok := manifest.Tables[table]; ok && rows != want {
+			return nil, fmt.Errorf("dump of %s has %d rows, the manifest says %d", table, rows, want)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// ReplaceDatabase moves the database file src over dst. dst is checkpointed
+// and closed first and its -wal and -shm files removed, so SQLite can't
+// replay the write-ahead log of the old database onto the new one.
+func ReplaceDatabase(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		if err := checkpointDatabase(dst); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dst + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove the write-ahead log of %s: %w", dst, err)
+		}
+	}
+
+	return os.Rename(src, dst)
+}
+
+// checkpointDatabase writes the write-ahead log of the database at path into
+// it. It fails while another process is using the database.
+func checkpointDatabase(path string) error {
+	conn, err := sqlx.Connect("sqlite3", DatabaseOptions{Path: path}.dsn())
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer conn.Close()
+
+	var busy, frames, checkpointed int
+	if err := conn.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &frames, &checkpointed); err != nil {
+		return fmt.Errorf("failed to checkpoint %s: %w", path, err)
+	}
+	if busy != 0 {
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+
+	return conn.Close()
+}
+
+func loadTable(tx *sqlx.Tx, dir, table string) (int, error) {
+	path := dumpFile(dir, table, false)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		path = dumpFile(dir, table, true)
+		f, err = os.Open(path)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		// Not in dumps of an older schema
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	_, columns, err := tableColumns(tx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	statements := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, statement := range statements {
+			statement.Close()
+		}
+	}()
+
+	count := 0
+	for {
+		var row map[string]any
+		if err := decoder.Decode(&row); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, fmt.Errorf("%s: row %d: %w", path, count+1, err)
+		}
+
+		names := make([]string, 0, len(row))
+		for name := range row {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		values := make([]any, len(names))
+		for i, name := range names {
+			if !columns[name] {
+				return 0, fmt.Errorf("%s: row %d: %s has no column %q", path, count+1, table, name)
+			}
+			values[i] = dumpValue(row[name])
+		}
+
+		// The migrations fill in genres
+		verb := "INSERT"
+		if table == "genres" {
+			verb = "INSERT OR REPLACE"
+		}
+		query := fmt.Sprintf("%s INTO %s (%s) VALUES (?%s)", verb, table, strings.Join(names, ", "), strings.Repeat(", ?", len(names)-1))
+		statement, ok := statements[query]
+		if !ok {
+			if statement, err = tx.Prepare(query); err != nil {
+				return 0, fmt.Errorf("%s: row %d: %w", path, count+1, err)
+			}
+			statements[query] = statement
+		}
+		if _, err := statement.Exec(values...); err != nil {
+			return 0, fmt.Errorf("%s: row %d: %w", path, count+1, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// dumpValue converts a JSON number back to the integer or float it was
+// dumped from. Integral floats dump like integers, the column affinity turns
+// them back into floats.
+func dumpValue(value any) any {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := number.Int64(); err == nil {
+		return i
+	}
+	f, _ := number.Float64()
+	return f
+}
@@ -0,0 +1,195 @@
+package tmdbankigenerator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tableContents returns the rows of table as SQL literals, which tells
+// integers, floats, text and NULL apart.
+func tableContents(t *testing.T, db *Database, table string) []string {
+	t.Helper()
+
+	primaryKey, columns, err := tableColumns(db.conn, table)
+	if err != nil {
+		t.Fatalf("tableColumns failed: %v", err)
+	}
+
+	var quoted []string
+	for column := range columns {
+		quoted = append(quoted, "quote("+column+")")
+	}
+	slices.Sort(quoted)
+
+	var rows []string
+	query := "SELECT " + strings.Join(quoted, " || ',' || ") + " FROM " + table + " ORDER BY " + strings.Join(primaryKey, ", ")
+	if err := db.conn.Select(&rows, query); err != nil {
+		t.Fatalf("%s failed: %v", query, err)
+	}
+	return rows
+}
+
+func TestDumpAndLoad(t *testing.T) {
+	indexer, db, _ := newTestIndexer(t)
+
+	popular := []PopularMovie{}
+	for _, movie := range testMovies {
+		popular = append(popular, PopularMovie{ID: int(movie.ID), Popularity: movie.Popularity})
+	}
+	if _, err := indexer.Index(context.Background(), popular); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if err := db.SetMetadata(MetadataMovieExportDate, "2024-01-02"); err != nil {
+		t.Fatalf("SetMetadata failed: %v", err)
+	}
+	// Values that don't survive JSON easily
+	if err := db.UpsertPeople([]Person{{ID: 1 << 60, Name: "Zoë \"Quoted\" <b>", Popularity: 0.1, AlsoKnownAs: []string{"ゾーイ"}}}); err != nil {
+		t.Fatalf("UpsertPeople failed: %v", err)
+	}
+
+	for _, gzipped := range []bool{false, true} {
+		name := "Plain"
+		if gzipped {
+			name = "Gzip"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "dump")
+			manifest, err := db.Dump(dir, DumpOptions{Gzip: gzipped})
+			if err != nil {
+				t.Fatalf("Dump failed: %v", err)
+			}
+			if manifest.SchemaVersion != LatestSchemaVersion() || manifest.Tables["movies"] != 2 || manifest.Tables["credits"] != 5 {
+				t.Errorf("unexpected manifest: %+v", manifest)
+			}
+
+			loaded, err := NewDatabase(DatabaseOptions{InMemory: true})
+			if err != nil {
+				t.Fatalf("NewDatabase failed: %v", err)
+			}
+			defer loaded.Close()
+
+			if _, err := loaded.Load(dir); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			for _, table := range dumpTables {
+				want, got := tableContents(t, db, table), tableContents(t, loaded, table)
+				if !slices.Equal(got, want) {
+					t.Errorf("%s changed by dumping and loading\ngot:  %v\nwant: %v", table, got, want)
+				}
+			}
+
+			if results, err := loaded.SearchPeople("zoe", 0); errors.Is(err, ErrSearchUnavailable) {
+				// Built without FTS5
+			} else if err != nil {
+				t.Fatalf("SearchPeople failed: %v", err)
+			} else if len(results) != 1 {
+				t.Errorf("expected the search index to be rebuilt, got %v", results)
+			}
+
+			again := filepath.Join(t.TempDir(), "again")
+			if _, err := loaded.Dump(again, DumpOptions{Gzip: gzipped}); err != nil {
+				t.Fatalf("Dump failed: %v", err)
+			}
+			for _, table := range dumpTables {
+				want, _ := os.ReadFile(dumpFile(dir, table, gzipped))
+				got, _ := os.ReadFile(dumpFile(again, table, gzipped))
+				if !bytes.Equal(got, want) {
+					t.Errorf("expected dumping %s twice to write the same file", table)
+				}
+			}
+
+			if _, err := loaded.Load(dir); err == nil {
+				t.Errorf("expected loading into a database that isn't empty to fail")
+			}
+		})
+	}
+
+	t.Run("Sorted", func(t *testing.T) {
+		dir := t.TempDir()
+		if _, err := db.Dump(dir, DumpOptions{}); err != nil {
+			t.Fatalf("Dump failed: %v", err)
+		}
+
+		data, err := os.ReadFile(dumpFile(dir, "movies", false))
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], `{"adult":false,`) || !strings.Contains(lines[0], `"id":550,`) {
+			t.Errorf("expected movies sorted by ID with sorted columns, got %v", lines)
+		}
+	})
+}
+
+func TestReplaceDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "data.db")
+	src := filepath.Join(dir, "data.db.load")
+
+	old, err := NewDatabase(DatabaseOptions{Path: dst, WAL: true})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	if err := old.UpsertPeople([]Person{{ID: 1, Name: "Old", Popularity: 1}}); err != nil {
+		t.Fatalf("UpsertPeople failed: %v", err)
+	}
+
+	// Keep the write-ahead log like a crashed writer leaves it behind
+	leftovers := map[string][]byte{}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		data, err := os.ReadFile(dst + suffix)
+		if err != nil {
+			t.Fatalf("expected a %s file: %v", suffix, err)
+		}
+		leftovers[suffix] = data
+	}
+	old.Close()
+	for suffix, data := range leftovers {
+		if err := os.WriteFile(dst+suffix, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := NewDatabase(DatabaseOptions{Path: src})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	if err := loaded.UpsertPeople([]Person{{ID: 2, Name: "New", Popularity: 1}}); err != nil {
+		t.Fatalf("UpsertPeople failed: %v", err)
+	}
+	loaded.Close()
+
+	if err := ReplaceDatabase(src, dst); err != nil {
+		t.Fatalf("ReplaceDatabase failed: %v", err)
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(dst + suffix); !os.IsNotExist(err) {
+			t.Errorf("expected the %s file to be removed, got %v", suffix, err)
+		}
+	}
+
+	db, err := NewDatabase(DatabaseOptions{Path: dst, WAL: true})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	people, err := db.GetPeople()
+	if err != nil {
+		t.Fatalf("GetPeople failed: %v", err)
+	}
+	if len(people) != 1 || people[0].Name != "New" {
+		t.Errorf("expected only the loaded person, got %+v", people)
+	}
+	if err := db.conn.Get(new(string), "PRAGMA integrity_check"); err != nil {
+		t.Errorf("integrity check failed: %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package tmdbankigenerator
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxMalformedLines is how many malformed lines an ExportReport keeps. The
+// rest are only counted.
+const maxMalformedLines = 100
+
+// ExportLineError is a line of an export file that couldn't be decoded.
+type ExportLineError struct {
+	Line int
+	Err  error
+}
+
+func (e *ExportLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *ExportLineError) Unwrap() error {
+	return e.Err
+}
+
+type ExportReport struct {
+	File string
+	// Lines read, including blank and malformed ones
+	Lines int
+	// Records that passed the filter
+	Kept           int
+	MalformedCount int
+	Malformed      []*ExportLineError
+}
+
+func (r *ExportReport) String() string {
+	return fmt.Sprintf("%s: read %d lines, kept %d records, skipped %d malformed lines", r.File, r.Lines, r.Kept, r.MalformedCount)
+}
+
+// exportRecord is a record of one of TMDB's daily ID exports.
+type exportRecord interface {
+	PopularMovie | PopularPerson
+	exportID() int
+}
+
+func (m PopularMovie) exportID() int  { return m.ID }
+func (p PopularPerson) exportID() int { return p.ID }
+
+// ReadExport streams one of TMDB's daily ID exports, which have one JSON
+// object per line and may be gzipped. Records for which keep returns true are
+// passed to yield, one at a time. Malformed lines are skipped and collected
+// in the report; only read errors and errors returned by yield stop reading.
+// keep may be nil to pass on every record.
+func ReadExport[T exportRecord](path string, keep func(T) bool, yield func(T) error) (*ExportReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer file.Close()
+
+	report := &ExportReport{File: path}
+
+	reader, err := decompress(bufio.NewReader(file))
+	if err != nil {
+		return report, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		report.Lines++
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record T
+		if err := json.Unmarshal(line, &record); err != nil {
+			report.malformed(err)
+			continue
+		}
+		if record.exportID() <= 0 {
+			report.malformed(errors.New("missing id"))
+			continue
+		}
+
+		if keep != nil && !keep(record) {
+			continue
+		}
+
+		report.Kept++
+		if err := yield(record); err != nil {
+			return report, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("failed to read %s after line %d: %w", path, report.Lines, err)
+	}
+
+	return report, nil
+}
+
+// LogMalformed logs the malformed lines of the export, if there were any.
+func (r *ExportReport) LogMalformed() {
+	if r.MalformedCount == 0 {
+		return
+	}
+
+	log.Println(r)
+	for _, err := range r.Malformed {
+		log.Printf("%s: %s", r.File, err)
+	}
+	if r.MalformedCount > len(r.Malformed) {
+		log.Printf("%s: and %d more malformed lines", r.File, r.MalformedCount-len(r.Malformed))
+	}
+}
+
+func (r *ExportReport) malformed(err error) {
+	r.MalformedCount++
+	if len(r.Malformed) < maxMalformedLines {
+		r.Malformed = append(r.Malformed, &ExportLineError{Line: r.Lines, Err: err})
+	}
+}
+
+// decompress transparently gunzips the reader if it starts with the gzip
+// magic number, so both the downloaded .json.gz and decompressed files work.
+func decompress(reader *bufio.Reader) (io.Reader, error) {
+	magic, err := reader.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(reader)
+	}
+
+	return reader, nil
+}
+
+type ExportKind string
+
+const (
+	MovieExport  ExportKind = "movie_ids"
+	PersonExport ExportKind = "person_ids"
+)
+
+type ExportFile struct {
+	Kind ExportKind
+	Path string
+	// Date of the export, zero if it can't be told from the file name
+	Date time.Time
+}
+
+var exportFileName = regexp.MustCompile(`^(movie_ids|person_ids)_(\d{2}_\d{2}_\d{4})\.json(\.gz)?$`)
+
+// parseExportFileName returns the kind and date of an export named like
+// TMDB names them, e.g. movie_ids_01_11_2025.json.gz.
+func parseExportFileName(name string) (ExportKind, time.Time, bool) {
+	match := exportFileName.FindStringSubmatch(name)
+	if match == nil {
+		return "", time.Time{}, false
+	}
+
+	date, err := time.Parse("01_02_2006", match[2])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	return ExportKind(match[1]), date, true
+}
+
+// FindLatestExport returns the newest export of the given kind in dir.
+// Uncompressed files are preferred over gzipped ones from the same day.
+func FindLatestExport(dir string, kind ExportKind) (ExportFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ExportFile{}, fmt.Errorf("failed to list exports: %w", err)
+	}
+
+	var latest ExportFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		entryKind, date, ok := parseExportFileName(entry.Name())
+		if !ok || entryKind != kind {
+			continue
+		}
+
+		if latest.Path == "" || date.After(latest.Date) ||
+			(date.Equal(latest.Date) && strings.HasSuffix(latest.Path, ".gz") && !strings.HasSuffix(entry.Name(), ".gz")) {
+			latest = ExportFile{Kind: kind, Path: filepath.Join(dir, entry.Name()), Date: date}
+		}
+	}
+
+	if latest.Path == "" {
+		return ExportFile{}, fmt.Errorf("no %s_MM_DD_YYYY.json(.gz) export found in %s, download one from http://files.tmdb.org/p/exports/", kind, dir)
+	}
+
+	return latest, nil
+}
+
+// Find returns the export of the given kind to use: the configured file if
+// there is one, the newest in the export directory otherwise. It warns when
+// the export is stale or from another day than the export of the other kind.
+func (c ExportsConfig) Find(kind ExportKind) (ExportFile, error) {
+	export, err := c.find(kind)
+	if err != nil {
+		return ExportFile{}, err
+	}
+
+	if export.Date.IsZero() {
+		return export, nil
+	}
+
+	if age := time.Since(export.Date); c.MaxAgeDays > 0 && age > time.Duration(c.MaxAgeDays)*24*time.Hour {
+		log.Printf("Warning: %s is %d days old", export.Path, int(age.Hours()/24))
+	}
+
+	other := PersonExport
+	if kind == PersonExport {
+		other = MovieExport
+	}
+	if otherExport, err := c.find(other); err == nil && !otherExport.Date.IsZero() && !otherExport.Date.Equal(export.Date) {
+		log.Printf("Warning: %s and %s are from different days", export.Path, otherExport.Path)
+	}
+
+	return export, nil
+}
+
+func (c ExportsConfig) find(kind ExportKind) (ExportFile, error) {
+	path := c.Movies
+	if kind == PersonExport {
+		path = c.People
+	}
+
+	if path == "" {
+		return FindLatestExport(c.Dir, kind)
+	}
+
+	export := ExportFile{Kind: kind, Path: path}
+	if _, date, ok := parseExportFileName(filepath.Base(path)); ok {
+		export.Date = date
+	}
+
+	return export, nil
+}
@@ -0,0 +1,148 @@
+package tmdbankigenerator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMovieExport = `{"adult":false,"id":550,"original_title":"Fight Club","popularity":61.4,"video":false}
+{"adult":false,"id":551,"original_title":"The Poseidon Adventure","popularity":12.1,"video":false}
+{"adult":false,"id":552,"original_title":"Broken
+{"adult":false,"original_title":"No ID","popularity":3.0,"video":false}
+
+{"adult":false,"id":553,"original_title":"Dogville","popularity":17.9,"video":false}
+`
+
+func writeExport(t *testing.T, name string, content string, gzipped bool) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create export: %v", err)
+	}
+	defer file.Close()
+
+	if !gzipped {
+		if _, err := file.WriteString(content); err != nil {
+			t.Fatalf("failed to write export: %v", err)
+		}
+		return path
+	}
+
+	writer := gzip.NewWriter(file)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write export: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
+	}
+
+	return path
+}
+
+func TestReadExport(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		name := "movie_ids.json"
+		if gzipped {
+			name += ".gz"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			path := writeExport(t, name, testMovieExport, gzipped)
+
+			var titles []string
+			report, err := ReadExport(path, func(movie PopularMovie) bool {
+				return movie.Popularity > 15
+			}, func(movie PopularMovie) error {
+				titles = append(titles, movie.Title)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ReadExport failed: %v", err)
+			}
+
+			if len(titles) != 2 || titles[0] != "Fight Club" || titles[1] != "Dogville" {
+				t.Errorf("unexpected movies: %v", titles)
+			}
+			if report.Lines != 6 || report.Kept != 2 {
+				t.Errorf("expected 6 lines and 2 kept records, got %d and %d", report.Lines, report.Kept)
+			}
+			if report.MalformedCount != 2 || report.Malformed[0].Line != 3 || report.Malformed[1].Line != 4 {
+				t.Errorf("expected lines 3 and 4 to be malformed, got %v", report.Malformed)
+			}
+		})
+	}
+}
+
+func TestGetTopMovies(t *testing.T) {
+	path := writeExport(t, "movie_ids.json", testMovieExport, false)
+
+	movies, _, err := GetTopMovies(path, 10, 2)
+	if err != nil {
+		t.Fatalf("GetTopMovies failed: %v", err)
+	}
+
+	if len(movies) != 2 || movies[0].ID != 550 || movies[1].ID != 553 {
+		t.Errorf("expected the two most popular movies, got %+v", movies)
+	}
+}
+
+func TestFindLatestExport(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"movie_ids_01_10_2025.json",
+		"movie_ids_01_11_2025.json.gz",
+		"movie_ids_01_11_2025.json",
+		"movie_ids_12_31_2024.json.gz",
+		"person_ids_02_01_2025.json.gz",
+		"movie_ids.json",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("failed to write export: %v", err)
+		}
+	}
+
+	export, err := FindLatestExport(dir, MovieExport)
+	if err != nil {
+		t.Fatalf("FindLatestExport failed: %v", err)
+	}
+	if filepath.Base(export.Path) != "movie_ids_01_11_2025.json" {
+		t.Errorf("expected movie_ids_01_11_2025.json, got %s", export.Path)
+	}
+	if export.Date != time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("expected date 2025-01-11, got %s", export.Date)
+	}
+
+	export, err = ExportsConfig{Dir: dir, People: "other/person_ids_03_01_2025.json", MaxAgeDays: 7}.Find(PersonExport)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if export.Path != "other/person_ids_03_01_2025.json" || export.Date.Month() != time.March {
+		t.Errorf("expected the configured person export, got %+v", export)
+	}
+
+	// The exports are from 2025, far older than 7 days
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	for _, maxAgeDays := range []int{7, 0} {
+		logs.Reset()
+		if _, err := (ExportsConfig{Dir: dir, MaxAgeDays: maxAgeDays}).Find(MovieExport); err != nil {
+			t.Fatalf("Find failed: %v", err)
+		}
+		if stale := strings.Contains(logs.String(), "days old"); stale != (maxAgeDays > 0) {
+			t.Errorf("with max_age_days = %d, expected a stale warning to be %v, got %q", maxAgeDays, maxAgeDays > 0, logs.String())
+		}
+	}
+
+	if _, err := FindLatestExport(t.TempDir(), PersonExport); err == nil {
+		t.Errorf("expected an error for a directory without exports")
+	}
+}
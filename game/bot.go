@@ -0,0 +1,195 @@
+package game
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// Difficulty is how hard a Bot plays.
+type Difficulty int
+
+const (
+	// Plays a random valid move
+	DifficultyEasy Difficulty = iota
+	// Plays the move leaving the opponent the fewest movies to follow with,
+	// preferring moves that use up a person
+	DifficultyMedium
+	// Plays like DifficultyMedium, but also looks at the opponent's replies
+	// and avoids moves that let them leave the bot without a move
+	DifficultyHard
+)
+
+var difficultyNames = map[Difficulty]string{
+	DifficultyEasy:   "easy",
+	DifficultyMedium: "medium",
+	DifficultyHard:   "hard",
+}
+
+func (d Difficulty) String() string {
+	if name, ok := difficultyNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("Difficulty(%d)", int(d))
+}
+
+// ParseDifficulty looks up a difficulty by name, ignoring case.
+func ParseDifficulty(name string) (Difficulty, bool) {
+	for difficulty, difficultyName := range difficultyNames {
+		if strings.EqualFold(difficultyName, strings.TrimSpace(name)) {
+			return difficulty, true
+		}
+	}
+	return 0, false
+}
+
+// Difficulties returns every difficulty, easiest first.
+func Difficulties() []Difficulty {
+	return []Difficulty{DifficultyEasy, DifficultyMedium, DifficultyHard}
+}
+
+// hardCandidates is how many of the best moves by their opponent's options
+// DifficultyHard looks past.
+const hardCandidates = 10
+
+// Bot is a computer opponent choosing the next movie of a game.
+type Bot struct {
+	difficulty Difficulty
+	rand       *rand.Rand
+}
+
+// NewBot returns a bot playing at difficulty, with random choices drawn
+// from source.
+func NewBot(difficulty Difficulty, source rand.Source) *Bot {
+	return &Bot{difficulty: difficulty, rand: rand.New(source)}
+}
+
+// Choice is a move a bot chose and why.
+type Choice struct {
+	Move   Move
+	Reason string
+}
+
+// candidate is a move with what it leaves the opponent.
+type candidate struct {
+	move Move
+	// Movies the opponent can follow the move with
+	options int
+	// People the move uses for the last time
+	exhausts []int
+	// Whether a reply of the opponent leaves the bot without a move
+	trapped bool
+}
+
+// Choose picks the next move of the game. It's false when there's none.
+// The game is left as it was.
+func (b *Bot) Choose(g *Game) (Choice, bool) {
+	moves := g.ValidMoves()
+	if len(moves) == 0 {
+		return Choice{}, false
+	}
+
+	if b.difficulty == DifficultyEasy {
+		move := moves[b.rand.IntN(len(moves))]
+		return Choice{Move: move, Reason: fmt.Sprintf("picked at random from %s", pluralMovies(len(moves)))}, true
+	}
+
+	candidates := make([]candidate, len(moves))
+	for i, move := range moves {
+		candidates[i] = candidate{move: move}
+		for _, personID := range move.PersonIDs {
+			if g.uses[personID] == MaxUses-1 {
+				candidates[i].exhausts = append(candidates[i].exhausts, personID)
+			}
+		}
+
+		g.mustPlay(move.MovieID)
+		candidates[i].options = g.validMoveCount(0)
+		g.undo()
+	}
+	// Stable, so equal candidates stay ordered like ValidMoves
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.options, b.options), cmp.Compare(len(b.exhausts), len(a.exhausts)))
+	})
+
+	best := candidates[0]
+	if b.difficulty == DifficultyHard && best.options > 0 {
+		considered := candidates[:min(len(candidates), hardCandidates)]
+		for i := range considered {
+			considered[i].trapped = g.trapped(considered[i].move)
+		}
+		if i := slices.IndexFunc(considered, func(c candidate) bool { return !c.trapped }); i >= 0 {
+			best = considered[i]
+		} else {
+			best = considered[0]
+		}
+	}
+
+	return Choice{Move: best.move, Reason: b.explain(g, best, candidates[0].options, len(moves))}, true
+}
+
+// trapped reports whether the opponent has a reply to move leaving no movie
+// to follow it with.
+func (g *Game) trapped(move Move) bool {
+	g.mustPlay(move.MovieID)
+	defer g.undo()
+
+	for _, reply := range g.ValidMoves() {
+		g.mustPlay(reply.MovieID)
+		stuck := g.validMoveCount(1) == 0
+		g.undo()
+		if stuck {
+			return true
+		}
+	}
+	return false
+}
+
+// mustPlay plays a move that's known to be valid.
+func (g *Game) mustPlay(movieID int) {
+	if _, err := g.Play(movieID); err != nil {
+		panic(fmt.Sprintf("playing valid move %d: %v", movieID, err))
+	}
+}
+
+// explain describes why the bot chose a move, given the fewest options any
+// move left and how many moves there were.
+func (b *Bot) explain(g *Game, chosen candidate, fewest, moves int) string {
+	var reasons []string
+	switch {
+	case chosen.options == 0:
+		reasons = append(reasons, "leaves no movie to follow it")
+	case chosen.options == fewest:
+		reasons = append(reasons, fmt.Sprintf("leaves %s to follow it, the fewest of %d moves", pluralMovies(chosen.options), moves))
+	default:
+		reasons = append(reasons, fmt.Sprintf("leaves %s to follow it, %d more than the fewest", pluralMovies(chosen.options), chosen.options-fewest))
+	}
+
+	if len(chosen.exhausts) > 0 {
+		names := make([]string, len(chosen.exhausts))
+		for i, personID := range chosen.exhausts {
+			person, _ := g.graph.Person(personID)
+			names[i] = person.Name
+		}
+		reasons = append(reasons, fmt.Sprintf("uses up %s", strings.Join(names, " and ")))
+	}
+
+	if b.difficulty == DifficultyHard && chosen.options > 0 {
+		if chosen.trapped {
+			reasons = append(reasons, "though each move I looked at has a reply I can't follow")
+		} else {
+			reasons = append(reasons, "and no reply to it is a movie I can't follow")
+		}
+	}
+
+	return strings.Join(reasons, ", ")
+}
+
+func pluralMovies(n int) string {
+	if n == 1 {
+		return "1 movie"
+	}
+	return fmt.Sprintf("%d movies", n)
+}
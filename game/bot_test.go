@@ -0,0 +1,123 @@
+package game
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/graph"
+)
+
+// newTrapGraph starts from movie 1. Playing 2 leaves the opponent only 3,
+// after which nothing follows. Playing 4 leaves them 5 and 6, which both
+// can be followed.
+func newTrapGraph() *graph.Graph {
+	links := &tmdbankigenerator.Links{}
+	for id := 1; id <= 8; id++ {
+		links.Movies = append(links.Movies, tmdbankigenerator.Movie{ID: id, Title: "Movie", Popularity: float32(10 - id)})
+	}
+	for personID, movieIDs := range map[int][]int{
+		10: {1, 2},
+		11: {1, 4},
+		12: {2, 3},
+		13: {4, 5, 6},
+		14: {5, 7},
+		15: {6, 8},
+	} {
+		links.People = append(links.People, tmdbankigenerator.Person{ID: personID, Name: "Person"})
+		for _, movieID := range movieIDs {
+			links.Credits = append(links.Credits, tmdbankigenerator.Credit{PersonID: personID, MovieID: movieID, JobType: tmdbankigenerator.JobTypeCast})
+		}
+	}
+	return graph.New(links)
+}
+
+func TestBot(t *testing.T) {
+	tests := []struct {
+		difficulty Difficulty
+		movieID    int
+		reason     string
+	}{
+		{DifficultyMedium, 2, "leaves 1 movie to follow it, the fewest of 2 moves"},
+		{DifficultyHard, 4, "no reply to it is a movie I can't follow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.difficulty.String(), func(t *testing.T) {
+			g, err := New(newTrapGraph(), 1)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			choice, ok := NewBot(tt.difficulty, rand.NewPCG(1, 2)).Choose(g)
+			if !ok {
+				t.Fatalf("expected a move")
+			}
+			if choice.Move.MovieID != tt.movieID {
+				t.Errorf("expected movie %d, got %+v", tt.movieID, choice)
+			}
+			if !strings.Contains(choice.Reason, tt.reason) {
+				t.Errorf("expected the reason to contain %q, got %q", tt.reason, choice.Reason)
+			}
+			if len(g.Moves()) != 1 || g.Played(choice.Move.MovieID) {
+				t.Errorf("expected the game to be left as it was, got %+v", g.Moves())
+			}
+		})
+	}
+
+	t.Run("Easy", func(t *testing.T) {
+		g, err := New(newTrapGraph(), 1)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		choice, ok := NewBot(DifficultyEasy, rand.NewPCG(1, 2)).Choose(g)
+		if !ok {
+			t.Fatalf("expected a move")
+		}
+		if _, err := g.Play(choice.Move.MovieID); err != nil {
+			t.Errorf("expected a valid move, got %+v: %v", choice, err)
+		}
+	})
+
+	t.Run("No move", func(t *testing.T) {
+		// From 3 only 2 can be played, and nothing follows it but 1
+		g, err := New(newTrapGraph(), 1)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		for _, movieID := range []int{2, 3} {
+			if _, err := g.Play(movieID); err != nil {
+				t.Fatalf("Play(%d) failed: %v", movieID, err)
+			}
+		}
+		if choice, ok := NewBot(DifficultyHard, rand.NewPCG(1, 2)).Choose(g); ok {
+			t.Errorf("expected no move from 3, got %+v", choice)
+		}
+	})
+
+	t.Run("Uses up", func(t *testing.T) {
+		g, err := New(newTrapGraph(), 4)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		// Only person 13 links 4 onwards, for the last time
+		g.uses[11] = MaxUses
+		g.uses[13] = MaxUses - 1
+
+		choice, ok := NewBot(DifficultyMedium, rand.NewPCG(1, 2)).Choose(g)
+		if !ok || choice.Move.MovieID != 5 || !strings.Contains(choice.Reason, "uses up Person") {
+			t.Errorf("expected 5 using up person 13, got %+v %t", choice, ok)
+		}
+	})
+}
+
+func TestParseDifficulty(t *testing.T) {
+	for _, difficulty := range Difficulties() {
+		if parsed, ok := ParseDifficulty(strings.ToUpper(difficulty.String())); !ok || parsed != difficulty {
+			t.Errorf("expected %s to parse, got %v %t", difficulty, parsed, ok)
+		}
+	}
+	if _, ok := ParseDifficulty("impossible"); ok {
+		t.Errorf("expected an unknown difficulty to fail")
+	}
+}
@@ -0,0 +1,180 @@
+// Package game plays Cine2Nerdle against the local database. Each movie
+// must share a person with the one before it, no movie can be played twice
+// and each person links at most MaxUses times.
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/JonasRothmann/cine2nerdle-trainer/graph"
+)
+
+// MaxUses is how many times a person can link movies in a game.
+const MaxUses = 3
+
+var (
+	ErrUnknownMovie = errors.New("movie isn't part of the game")
+	ErrMoviePlayed  = errors.New("movie was already played")
+	ErrNoLink       = errors.New("no one links the movies")
+	ErrLinksUsedUp  = fmt.Errorf("everyone linking the movies was used %d times", MaxUses)
+)
+
+// Move is a movie played, with the people that linked it to the movie
+// before. The first move of a game has no people.
+type Move struct {
+	MovieID int
+	// Most popular first
+	PersonIDs []int
+}
+
+type Game struct {
+	graph  *graph.Graph
+	moves  []Move
+	played map[int]bool
+	uses   map[int]int
+}
+
+// New starts a game on the graph with the movie startID.
+func New(g *graph.Graph, startID int) (*Game, error) {
+	if _, ok := g.Movie(startID); !ok {
+		return nil, fmt.Errorf("movie %d: %w", startID, ErrUnknownMovie)
+	}
+
+	return &Game{
+		graph:  g,
+		moves:  []Move{{MovieID: startID}},
+		played: map[int]bool{startID: true},
+		uses:   make(map[int]int),
+	}, nil
+}
+
+func (g *Game) Graph() *graph.Graph {
+	return g.graph
+}
+
+// Current returns the movie the next move must link to.
+func (g *Game) Current() int {
+	return g.moves[len(g.moves)-1].MovieID
+}
+
+// Moves returns the moves so far, the starting movie first. The slice must
+// not be modified.
+func (g *Game) Moves() []Move {
+	return g.moves
+}
+
+func (g *Game) Played(movieID int) bool {
+	return g.played[movieID]
+}
+
+// Uses returns how many times a person linked movies so far.
+func (g *Game) Uses(personID int) int {
+	return g.uses[personID]
+}
+
+// Links returns the people that would link movieID to the current movie,
+// most popular first, or why it can't be played.
+func (g *Game) Links(movieID int) ([]int, error) {
+	if _, ok := g.graph.Movie(movieID); !ok {
+		return nil, ErrUnknownMovie
+	}
+	if g.played[movieID] {
+		return nil, ErrMoviePlayed
+	}
+
+	people := g.graph.Connectors(g.Current(), movieID)
+	if len(people) == 0 {
+		return nil, ErrNoLink
+	}
+
+	var links []int
+	for _, personID := range people {
+		if g.uses[personID] < MaxUses {
+			links = append(links, personID)
+		}
+	}
+	if len(links) == 0 {
+		return nil, ErrLinksUsedUp
+	}
+
+	return links, nil
+}
+
+// Play plays movieID, using up one use of every person linking it that has
+// uses left, as Cine2Nerdle counts every link between the two movies.
+func (g *Game) Play(movieID int) (Move, error) {
+	links, err := g.Links(movieID)
+	if err != nil {
+		return Move{}, err
+	}
+
+	for _, personID := range links {
+		g.uses[personID]++
+	}
+	g.played[movieID] = true
+
+	move := Move{MovieID: movieID, PersonIDs: links}
+	g.moves = append(g.moves, move)
+	return move, nil
+}
+
+// ValidMoves returns the movies that can be played next with the people
+// that would link them, ordered like graph.Neighbors.
+func (g *Game) ValidMoves() []Move {
+	var moves []Move
+	for _, neighbor := range g.graph.Neighbors(g.Current()) {
+		if g.played[neighbor.MovieID] {
+			continue
+		}
+
+		var links []int
+		for _, personID := range neighbor.PersonIDs {
+			if g.uses[personID] < MaxUses {
+				links = append(links, personID)
+			}
+		}
+		if len(links) > 0 {
+			moves = append(moves, Move{MovieID: neighbor.MovieID, PersonIDs: links})
+		}
+	}
+	return moves
+}
+
+// Over reports whether no movie can be played next.
+func (g *Game) Over() bool {
+	return g.validMoveCount(1) == 0
+}
+
+// validMoveCount counts the movies that can be played next, like
+// len(ValidMoves()) but without ordering them. It stops counting at limit
+// when it's positive.
+func (g *Game) validMoveCount(limit int) int {
+	current := g.Current()
+	seen := make(map[int]bool)
+	for _, personID := range g.graph.MoviePeople(current) {
+		if g.uses[personID] >= MaxUses {
+			continue
+		}
+		for _, movieID := range g.graph.PersonMovies(personID) {
+			if g.played[movieID] || seen[movieID] {
+				continue
+			}
+			seen[movieID] = true
+			if len(seen) == limit {
+				return limit
+			}
+		}
+	}
+	return len(seen)
+}
+
+// undo takes back the last move.
+func (g *Game) undo() {
+	move := g.moves[len(g.moves)-1]
+	g.moves = g.moves[:len(g.moves)-1]
+	delete(g.played, move.MovieID)
+	for _, personID := range move.PersonIDs {
+		g.uses[personID]--
+	}
+}
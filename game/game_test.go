@@ -0,0 +1,87 @@
+package game
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/graph"
+)
+
+// newTestGraph links movies 1 to 5 through Hub, 1 and 2 also through Star,
+// and leaves movie 6 on its own.
+func newTestGraph() *graph.Graph {
+	links := &tmdbankigenerator.Links{
+		People: []tmdbankigenerator.Person{
+			{ID: 10, Name: "Hub", Popularity: 5},
+			{ID: 11, Name: "Star", Popularity: 9},
+			{ID: 12, Name: "Loner", Popularity: 1},
+		},
+	}
+	for id := 1; id <= 6; id++ {
+		links.Movies = append(links.Movies, tmdbankigenerator.Movie{ID: id, Title: "Movie", Popularity: float32(10 - id)})
+		if id <= 5 {
+			links.Credits = append(links.Credits, tmdbankigenerator.Credit{PersonID: 10, MovieID: id, JobType: tmdbankigenerator.JobTypeCast})
+		}
+	}
+	links.Credits = append(links.Credits,
+		tmdbankigenerator.Credit{PersonID: 11, MovieID: 1, JobType: tmdbankigenerator.JobTypeCast},
+		tmdbankigenerator.Credit{PersonID: 11, MovieID: 2, JobType: tmdbankigenerator.JobTypeCast},
+		tmdbankigenerator.Credit{PersonID: 12, MovieID: 6, JobType: tmdbankigenerator.JobTypeCast},
+	)
+	return graph.New(links)
+}
+
+func TestGame(t *testing.T) {
+	if _, err := New(newTestGraph(), 99); !errors.Is(err, ErrUnknownMovie) {
+		t.Errorf("expected an unknown start to fail with ErrUnknownMovie, got %v", err)
+	}
+
+	g, err := New(newTestGraph(), 1)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if moves := g.ValidMoves(); len(moves) != 4 || moves[0].MovieID != 2 || !slices.Equal(moves[0].PersonIDs, []int{11, 10}) {
+		t.Errorf("expected 4 valid moves, Movie 2 through both people first, got %+v", moves)
+	}
+
+	move, err := g.Play(2)
+	if err != nil {
+		t.Fatalf("Play(2) failed: %v", err)
+	}
+	if !slices.Equal(move.PersonIDs, []int{11, 10}) {
+		t.Errorf("expected both people to link 1 and 2, got %v", move.PersonIDs)
+	}
+
+	tests := []struct {
+		name    string
+		movieID int
+		err     error
+	}{
+		{"Played", 1, ErrMoviePlayed},
+		{"Unknown", 99, ErrUnknownMovie},
+		{"Unlinked", 6, ErrNoLink},
+		{"Second use", 3, nil},
+		{"Third use", 4, nil},
+		{"Used up", 5, ErrLinksUsedUp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := g.Play(tt.movieID); !errors.Is(err, tt.err) {
+				t.Errorf("expected Play(%d) to return %v, got %v", tt.movieID, tt.err, err)
+			}
+		})
+	}
+
+	if g.Uses(10) != MaxUses || g.Uses(11) != 1 {
+		t.Errorf("expected Hub to be used up and Star used once, got %d and %d", g.Uses(10), g.Uses(11))
+	}
+	if g.Current() != 4 || len(g.Moves()) != 4 {
+		t.Errorf("expected 4 moves ending on movie 4, got %+v", g.Moves())
+	}
+	if !g.Over() {
+		t.Errorf("expected the game to be over, got valid moves %+v", g.ValidMoves())
+	}
+}
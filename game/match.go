@@ -0,0 +1,79 @@
+package game
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/graph"
+)
+
+// Matcher matches typed titles to the movies of a graph, searching the
+// titles in the database so it works offline.
+type Matcher struct {
+	db    *tmdbankigenerator.Database
+	graph *graph.Graph
+}
+
+func NewMatcher(db *tmdbankigenerator.Database, g *graph.Graph) *Matcher {
+	return &Matcher{db: db, graph: g}
+}
+
+// releaseYear matches a release year in parentheses ending a title, as in
+// "Dune (2021)".
+var releaseYear = regexp.MustCompile(`\s*\((\d{4})\)\s*$`)
+
+// Match returns the movies of the graph a typed title may mean, best match
+// first. Only the best kind of match is returned: when a title matches
+// exactly, movies merely starting with its words aren't. A release year in
+// parentheses after the title narrows the movies down.
+func (m *Matcher) Match(title string) ([]tmdbankigenerator.MovieSearchResult, error) {
+	year := 0
+	if match := releaseYear.FindStringSubmatch(title); match != nil {
+		year, _ = strconv.Atoi(match[1])
+		title = title[:len(title)-len(match[0])]
+	}
+	if strings.TrimSpace(title) == "" {
+		return nil, nil
+	}
+
+	results, err := m.db.SearchMovies(title, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []tmdbankigenerator.MovieSearchResult
+	for _, result := range results {
+		if _, ok := m.graph.Movie(result.ID); !ok {
+			continue
+		}
+		if year != 0 && result.ReleaseDate.Year() != year {
+			continue
+		}
+		// Results are ordered by match
+		if len(matches) > 0 && result.Match != matches[0].Match {
+			break
+		}
+		matches = append(matches, result)
+	}
+
+	return matches, nil
+}
+
+// Resolve picks the movie a typed title means in the game: the best match
+// that can be played next, or the best match when none can so the move
+// fails with why. It's false when no movie matches the title.
+func (m *Matcher) Resolve(g *Game, title string) (int, bool, error) {
+	matches, err := m.Match(title)
+	if err != nil || len(matches) == 0 {
+		return 0, false, err
+	}
+
+	for _, match := range matches {
+		if _, err := g.Links(match.ID); err == nil {
+			return match.ID, true, nil
+		}
+	}
+	return matches[0].ID, true, nil
+}
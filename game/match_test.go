@@ -0,0 +1,100 @@
+package game
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+	"github.com/JonasRothmann/cine2nerdle-trainer/graph"
+)
+
+func TestMatcher(t *testing.T) {
+	db, err := tmdbankigenerator.NewDatabase(tmdbankigenerator.DatabaseOptions{InMemory: true})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	date := func(year int) time.Time { return time.Date(year, 6, 1, 0, 0, 0, 0, time.UTC) }
+	if err := db.UpsertMovies([]tmdbankigenerator.Movie{
+		{ID: 1, Title: "Dune", Popularity: 50, ReleaseDate: date(2021)},
+		{ID: 2, Title: "Dune", Popularity: 20, ReleaseDate: date(1984)},
+		{ID: 3, Title: "Dune: Part Two", Popularity: 60, ReleaseDate: date(2024)},
+		{ID: 4, Title: "Arrival", Popularity: 40, ReleaseDate: date(2016)},
+		{ID: 5, Title: "Blue Velvet", Popularity: 30, ReleaseDate: date(1986)},
+		// Not in the graph
+		{ID: 6, Title: "Dune World", Popularity: 1, ReleaseDate: date(2021)},
+	}); err != nil {
+		t.Fatalf("UpsertMovies failed: %v", err)
+	}
+	if err := db.UpsertPeople([]tmdbankigenerator.Person{
+		{ID: 10, Name: "Denis Villeneuve", Popularity: 10},
+		{ID: 11, Name: "Kyle MacLachlan", Popularity: 10},
+	}); err != nil {
+		t.Fatalf("UpsertPeople failed: %v", err)
+	}
+	if err := db.UpsertCredits([]tmdbankigenerator.Credit{
+		{PersonID: 10, MovieID: 1, JobType: tmdbankigenerator.JobTypeDirector},
+		{PersonID: 10, MovieID: 3, JobType: tmdbankigenerator.JobTypeDirector},
+		{PersonID: 10, MovieID: 4, JobType: tmdbankigenerator.JobTypeDirector},
+		{PersonID: 11, MovieID: 2, JobType: tmdbankigenerator.JobTypeCast},
+		{PersonID: 11, MovieID: 5, JobType: tmdbankigenerator.JobTypeCast},
+	}); err != nil {
+		t.Fatalf("UpsertCredits failed: %v", err)
+	}
+
+	g, err := graph.Load(db, tmdbankigenerator.LinkQuery{MinMoviePopularity: 10})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	matcher := NewMatcher(db, g)
+	if _, err := matcher.Match("dune"); errors.Is(err, tmdbankigenerator.ErrSearchUnavailable) {
+		t.Skip("SQLite was built without FTS5, build with -tags sqlite_fts5")
+	}
+
+	ids := func(title string) []int {
+		t.Helper()
+		matches, err := matcher.Match(title)
+		if err != nil {
+			t.Fatalf("Match(%q) failed: %v", title, err)
+		}
+		var ids []int
+		for _, match := range matches {
+			ids = append(ids, match.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		title string
+		ids   []int
+	}{
+		{"dune", []int{1, 2}},
+		{"Dune (1984)", []int{2}},
+		{"dune part", []int{3}},
+		{"arival", nil},
+		{"   ", nil},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.title); !slices.Equal(got, tt.ids) {
+			t.Errorf("expected %q to match %v, got %v", tt.title, tt.ids, got)
+		}
+	}
+
+	// From Blue Velvet only the 1984 Dune can be played
+	game, err := New(g, 5)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if id, ok, err := matcher.Resolve(game, "Dune"); err != nil || !ok || id != 2 {
+		t.Errorf("expected Dune to resolve to the playable 1984 one, got %d %t %v", id, ok, err)
+	}
+	if id, ok, err := matcher.Resolve(game, "Arrival"); err != nil || !ok || id != 4 {
+		t.Errorf("expected Arrival to resolve even though it can't be played, got %d %t %v", id, ok, err)
+	}
+	if _, ok, err := matcher.Resolve(game, "Nothing Like It"); err != nil || ok {
+		t.Errorf("expected no match, got %t %v", ok, err)
+	}
+}
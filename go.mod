@@ -3,14 +3,18 @@ module github.com/JonasRothmann/cine2nerdle-trainer
 go 1.22.5
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/JonasRothmann/ankiconnect v0.0.0-00010101000000-000000000000
 	github.com/fatih/set v0.2.1
 	github.com/jmoiron/sqlx v1.4.0
 	github.com/joho/godotenv v1.5.1
 	github.com/mattn/go-sqlite3 v1.14.24
 	github.com/pkg/errors v0.9.1
+	github.com/privatesquare/bkst-go-utils v1.5.4
+	github.com/spf13/cobra v1.8.1
 	gitlab.com/metakeule/fmtdate v1.2.2
 	golang.org/x/sync v0.10.0
+	golang.org/x/text v0.19.0
 	golang.org/x/time v0.9.0
 )
 
@@ -25,13 +29,14 @@ require (
 	github.com/go-playground/validator/v10 v10.4.1 // indirect
 	github.com/go-resty/resty/v2 v2.7.0 // indirect
 	github.com/golang/protobuf v1.3.3 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/jarcoal/httpmock v1.0.8 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/leodido/go-urn v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/privatesquare/bkst-go-utils v1.5.4 // indirect
 	github.com/rogpeppe/go-internal v1.13.1 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/ugorji/go/codec v1.1.7 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
@@ -39,7 +44,6 @@ require (
 	golang.org/x/crypto v0.28.0 // indirect
 	golang.org/x/net v0.30.0 // indirect
 	golang.org/x/sys v0.26.0 // indirect
-	golang.org/x/text v0.19.0 // indirect
 	gopkg.in/yaml.v2 v2.2.8 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"math/rand/v2"
+)
+
+// Betweenness estimates how many shortest chains between two movies pass
+// through each movie and person, with Brandes' algorithm over the chains
+// starting from samples movies drawn with r. Every movie is a start when
+// samples isn't positive or exceeds the movies, otherwise the counts are
+// scaled up to estimate that.
+func (g *Graph) Betweenness(samples int, r *rand.Rand) (movies, people map[int]float64) {
+	movieIDs := g.MovieIDs()
+	personIDs := g.PersonIDs()
+
+	// Movies are nodes 0 to len(movieIDs)-1, people the nodes after them
+	nodes := len(movieIDs) + len(personIDs)
+	index := make(map[int]int, len(movieIDs))
+	for i, id := range movieIDs {
+		index[id] = i
+	}
+	personIndex := make(map[int]int, len(personIDs))
+	for i, id := range personIDs {
+		personIndex[id] = len(movieIDs) + i
+	}
+
+	adjacent := make([][]int, nodes)
+	for i, id := range movieIDs {
+		for _, personID := range g.movies[id].people {
+			adjacent[i] = append(adjacent[i], personIndex[personID])
+		}
+	}
+	for i, id := range personIDs {
+		for _, movieID := range g.people[id].movies {
+			adjacent[len(movieIDs)+i] = append(adjacent[len(movieIDs)+i], index[movieID])
+		}
+	}
+
+	sources := make([]int, len(movieIDs))
+	for i := range sources {
+		sources[i] = i
+	}
+	scale := 1.0
+	if samples > 0 && samples < len(sources) {
+		r.Shuffle(len(sources), func(i, j int) { sources[i], sources[j] = sources[j], sources[i] })
+		sources = sources[:samples]
+		scale = float64(len(movieIDs)) / float64(samples)
+	}
+
+	centrality := make([]float64, nodes)
+	var (
+		stack       = make([]int, 0, nodes)
+		queue       = make([]int, 0, nodes)
+		predecessor = make([][]int, nodes)
+		paths       = make([]float64, nodes)
+		distance    = make([]int, nodes)
+		dependency  = make([]float64, nodes)
+	)
+	for _, source := range sources {
+		stack = stack[:0]
+		queue = queue[:0]
+		for i := range nodes {
+			predecessor[i] = predecessor[i][:0]
+			paths[i] = 0
+			distance[i] = -1
+			dependency[i] = 0
+		}
+		paths[source] = 1
+		distance[source] = 0
+
+		queue = append(queue, source)
+		for head := 0; head < len(queue); head++ {
+			v := queue[head]
+			stack = append(stack, v)
+			for _, w := range adjacent[v] {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					queue = append(queue, w)
+				}
+				if distance[w] == distance[v]+1 {
+					paths[w] += paths[v]
+					predecessor[w] = append(predecessor[w], v)
+				}
+			}
+		}
+
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range predecessor[w] {
+				// Only chains ending at a movie count
+				target := 0.0
+				if w < len(movieIDs) {
+					target = 1
+				}
+				dependency[v] += paths[v] / paths[w] * (target + dependency[w])
+			}
+			if w != source {
+				centrality[w] += dependency[w]
+			}
+		}
+	}
+
+	// Each chain is counted from both its ends
+	movies = make(map[int]float64, len(movieIDs))
+	for i, id := range movieIDs {
+		movies[id] = centrality[i] * scale / 2
+	}
+	people = make(map[int]float64, len(personIDs))
+	for i, id := range personIDs {
+		people[id] = centrality[len(movieIDs)+i] * scale / 2
+	}
+	return movies, people
+}
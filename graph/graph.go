@@ -0,0 +1,279 @@
+// Package graph chains movies through the people they share, the way
+// Cine2Nerdle does. The graph is bipartite: movies link to the people
+// credited on them and people to their movies, so two movies are one link
+// apart when they share a person.
+package graph
+
+import (
+	"cmp"
+	"slices"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+)
+
+type Graph struct {
+	movies map[int]*movieNode
+	people map[int]*personNode
+}
+
+type movieNode struct {
+	movie tmdbankigenerator.Movie
+	// Most popular first
+	people []int
+}
+
+type personNode struct {
+	person tmdbankigenerator.Person
+	// Most popular first
+	movies []int
+	// Job types of the person, by movie
+	jobTypes map[int][]tmdbankigenerator.JobType
+}
+
+// Load builds the graph of the movies, people and credits selected by query.
+func Load(db *tmdbankigenerator.Database, query tmdbankigenerator.LinkQuery) (*Graph, error) {
+	links, err := db.Links(query)
+	if err != nil {
+		return nil, err
+	}
+	return New(links), nil
+}
+
+// New builds the graph of links. Credits of unknown movies or people are
+// left out.
+func New(links *tmdbankigenerator.Links) *Graph {
+	g := &Graph{
+		movies: make(map[int]*movieNode, len(links.Movies)),
+		people: make(map[int]*personNode, len(links.People)),
+	}
+
+	for _, movie := range links.Movies {
+		g.movies[movie.ID] = &movieNode{movie: movie}
+	}
+	for _, person := range links.People {
+		g.people[person.ID] = &personNode{person: person, jobTypes: make(map[int][]tmdbankigenerator.JobType)}
+	}
+
+	for _, credit := range links.Credits {
+		movie, ok := g.movies[credit.MovieID]
+		if !ok {
+			continue
+		}
+		person, ok := g.people[credit.PersonID]
+		if !ok {
+			continue
+		}
+
+		// A person with several jobs on a movie is one link
+		if _, ok := person.jobTypes[credit.MovieID]; !ok {
+			movie.people = append(movie.people, credit.PersonID)
+			person.movies = append(person.movies, credit.MovieID)
+		}
+		person.jobTypes[credit.MovieID] = append(person.jobTypes[credit.MovieID], credit.JobType)
+	}
+
+	for _, movie := range g.movies {
+		slices.SortFunc(movie.people, g.comparePeople)
+	}
+	for _, person := range g.people {
+		slices.SortFunc(person.movies, g.compareMovies)
+	}
+
+	return g
+}
+
+// compareMovies orders movies by popularity, most popular first.
+func (g *Graph) compareMovies(a, b int) int {
+	return cmp.Or(cmp.Compare(g.movies[b].movie.Popularity, g.movies[a].movie.Popularity), cmp.Compare(a, b))
+}
+
+// comparePeople orders people by popularity, most popular first.
+func (g *Graph) comparePeople(a, b int) int {
+	return cmp.Or(cmp.Compare(g.people[b].person.Popularity, g.people[a].person.Popularity), cmp.Compare(a, b))
+}
+
+func (g *Graph) Movie(id int) (tmdbankigenerator.Movie, bool) {
+	node, ok := g.movies[id]
+	if !ok {
+		return tmdbankigenerator.Movie{}, false
+	}
+	return node.movie, true
+}
+
+func (g *Graph) Person(id int) (tmdbankigenerator.Person, bool) {
+	node, ok := g.people[id]
+	if !ok {
+		return tmdbankigenerator.Person{}, false
+	}
+	return node.person, true
+}
+
+// MovieIDs returns every movie of the graph, ordered by ID.
+func (g *Graph) MovieIDs() []int {
+	ids := make([]int, 0, len(g.movies))
+	for id := range g.movies {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// PersonIDs returns every person of the graph, ordered by ID.
+func (g *Graph) PersonIDs() []int {
+	ids := make([]int, 0, len(g.people))
+	for id := range g.people {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// MoviePeople returns the people credited on a movie, most popular first.
+// The slice must not be modified.
+func (g *Graph) MoviePeople(movieID int) []int {
+	if node, ok := g.movies[movieID]; ok {
+		return node.people
+	}
+	return nil
+}
+
+// PersonMovies returns the movies of a person, most popular first. The
+// slice must not be modified.
+func (g *Graph) PersonMovies(personID int) []int {
+	if node, ok := g.people[personID]; ok {
+		return node.movies
+	}
+	return nil
+}
+
+// JobTypes returns what a person did on a movie, nil if they aren't
+// credited on it.
+func (g *Graph) JobTypes(personID, movieID int) []tmdbankigenerator.JobType {
+	if node, ok := g.people[personID]; ok {
+		return node.jobTypes[movieID]
+	}
+	return nil
+}
+
+// Connectors returns the people credited on both movies, most popular first.
+func (g *Graph) Connectors(a, b int) []int {
+	if a == b {
+		return nil
+	}
+
+	var people []int
+	for _, personID := range g.MoviePeople(a) {
+		if _, ok := g.people[personID].jobTypes[b]; ok {
+			people = append(people, personID)
+		}
+	}
+	return people
+}
+
+// Neighbor is a movie one link away, with the people linking to it.
+type Neighbor struct {
+	MovieID int
+	// Most popular first
+	PersonIDs []int
+}
+
+// Neighbors returns the movies sharing a person with movieID, the ones
+// sharing the most people first, then the most popular.
+func (g *Graph) Neighbors(movieID int) []Neighbor {
+	byMovie := make(map[int]*Neighbor)
+	var neighbors []*Neighbor
+	for _, personID := range g.MoviePeople(movieID) {
+		for _, otherID := range g.people[personID].movies {
+			if otherID == movieID {
+				continue
+			}
+			neighbor, ok := byMovie[otherID]
+			if !ok {
+				neighbor = &Neighbor{MovieID: otherID}
+				byMovie[otherID] = neighbor
+				neighbors = append(neighbors, neighbor)
+			}
+			neighbor.PersonIDs = append(neighbor.PersonIDs, personID)
+		}
+	}
+
+	result := make([]Neighbor, len(neighbors))
+	for i, neighbor := range neighbors {
+		result[i] = *neighbor
+	}
+	slices.SortFunc(result, func(a, b Neighbor) int {
+		return cmp.Or(cmp.Compare(len(b.PersonIDs), len(a.PersonIDs)), g.compareMovies(a.MovieID, b.MovieID))
+	})
+
+	return result
+}
+
+// Chain is a path of movies, each linked to the next through a person.
+type Chain struct {
+	MovieIDs []int
+	// PersonIDs[i] links MovieIDs[i] and MovieIDs[i+1]
+	PersonIDs []int
+}
+
+// Links returns how many links the chain has.
+func (c Chain) Links() int {
+	return len(c.PersonIDs)
+}
+
+// ShortestChain returns a chain from one movie to another with the fewest
+// links, through the most popular people and movies when there are several.
+// It's false when no chain connects them.
+func (g *Graph) ShortestChain(from, to int) (Chain, bool) {
+	if _, ok := g.movies[from]; !ok {
+		return Chain{}, false
+	}
+	if _, ok := g.movies[to]; !ok {
+		return Chain{}, false
+	}
+	if from == to {
+		return Chain{MovieIDs: []int{from}}, true
+	}
+
+	type step struct {
+		movieID  int
+		personID int
+	}
+	// How each movie was first reached
+	previous := map[int]step{from: {}}
+	expanded := make(map[int]bool)
+
+	queue := []int{from}
+	for len(queue) > 0 {
+		movieID := queue[0]
+		queue = queue[1:]
+
+		for _, personID := range g.movies[movieID].people {
+			// Every movie of a person is reached the first time they're
+			// expanded
+			if expanded[personID] {
+				continue
+			}
+			expanded[personID] = true
+
+			for _, nextID := range g.people[personID].movies {
+				if _, ok := previous[nextID]; ok {
+					continue
+				}
+				previous[nextID] = step{movieID: movieID, personID: personID}
+				if nextID == to {
+					chain := Chain{MovieIDs: []int{to}}
+					for id := to; id != from; id = previous[id].movieID {
+						chain.MovieIDs = append(chain.MovieIDs, previous[id].movieID)
+						chain.PersonIDs = append(chain.PersonIDs, previous[id].personID)
+					}
+					slices.Reverse(chain.MovieIDs)
+					slices.Reverse(chain.PersonIDs)
+					return chain, true
+				}
+				queue = append(queue, nextID)
+			}
+		}
+	}
+
+	return Chain{}, false
+}
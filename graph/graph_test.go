@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+
+	tmdbankigenerator "github.com/JonasRothmann/cine2nerdle-trainer"
+)
+
+func newTestGraph(jobTypes ...tmdbankigenerator.JobType) *Graph {
+	links := &tmdbankigenerator.Links{
+		Movies: []tmdbankigenerator.Movie{
+			{ID: 1, Title: "Start", Popularity: 50},
+			{ID: 2, Title: "Middle", Popularity: 40},
+			{ID: 3, Title: "Later", Popularity: 30},
+			{ID: 4, Title: "End", Popularity: 20},
+			{ID: 5, Title: "Island", Popularity: 10},
+		},
+		People: []tmdbankigenerator.Person{
+			{ID: 10, Name: "Lead", Popularity: 5},
+			{ID: 11, Name: "Star", Popularity: 9},
+			{ID: 12, Name: "Regular", Popularity: 3},
+			{ID: 13, Name: "Director", Popularity: 1},
+			{ID: 14, Name: "Loner", Popularity: 1},
+		},
+	}
+	for _, credit := range []tmdbankigenerator.Credit{
+		{PersonID: 10, MovieID: 1, JobType: tmdbankigenerator.JobTypeCast},
+		{PersonID: 10, MovieID: 2, JobType: tmdbankigenerator.JobTypeCast},
+		{PersonID: 10, MovieID: 2, JobType: tmdbankigenerator.JobTypeDirector},
+		{PersonID: 11, MovieID: 1, JobType: tmdbankigenerator.JobTypeCast},
+		{PersonID: 11, MovieID: 2, JobType: tmdbankigenerator.JobTypeCast},
+		{PersonID: 12, MovieID: 2, JobType: tmdbankigenerator.JobTypeCast},
+		{PersonID: 12, MovieID: 3, JobType: tmdbankigenerator.JobTypeCast},
+		{PersonID: 13, MovieID: 3, JobType: tmdbankigenerator.JobTypeDirector},
+		{PersonID: 13, MovieID: 4, JobType: tmdbankigenerator.JobTypeDirector},
+		{PersonID: 14, MovieID: 5, JobType: tmdbankigenerator.JobTypeCast},
+		// Unknown movie
+		{PersonID: 14, MovieID: 99, JobType: tmdbankigenerator.JobTypeCast},
+	} {
+		if len(jobTypes) == 0 || slices.Contains(jobTypes, credit.JobType) {
+			links.Credits = append(links.Credits, credit)
+		}
+	}
+	return New(links)
+}
+
+func TestShortestChain(t *testing.T) {
+	g := newTestGraph()
+
+	tests := []struct {
+		name     string
+		from, to int
+		ok       bool
+		movies   []int
+		people   []int
+	}{
+		{"Same movie", 1, 1, true, []int{1}, nil},
+		{"One link", 1, 2, true, []int{1, 2}, []int{11}},
+		{"Three links", 1, 4, true, []int{1, 2, 3, 4}, []int{11, 12, 13}},
+		{"Backwards", 4, 1, true, []int{4, 3, 2, 1}, []int{13, 12, 11}},
+		{"Unconnected", 1, 5, false, nil, nil},
+		{"Unknown movie", 1, 99, false, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, ok := g.ShortestChain(tt.from, tt.to)
+			if ok != tt.ok {
+				t.Fatalf("expected ok %t, got %t", tt.ok, ok)
+			}
+			if !slices.Equal(chain.MovieIDs, tt.movies) || !slices.Equal(chain.PersonIDs, tt.people) {
+				t.Errorf("expected movies %v through %v, got %+v", tt.movies, tt.people, chain)
+			}
+			if chain.Links() != len(tt.people) {
+				t.Errorf("expected %d links, got %d", len(tt.people), chain.Links())
+			}
+		})
+	}
+
+	t.Run("Job types", func(t *testing.T) {
+		g := newTestGraph(tmdbankigenerator.JobTypeCast)
+		if chain, ok := g.ShortestChain(1, 4); ok {
+			t.Errorf("expected no chain through cast only, got %+v", chain)
+		}
+		if _, ok := g.ShortestChain(1, 3); !ok {
+			t.Errorf("expected a chain through cast")
+		}
+	})
+}
+
+func TestNeighbors(t *testing.T) {
+	g := newTestGraph()
+
+	neighbors := g.Neighbors(2)
+	if len(neighbors) != 2 {
+		t.Fatalf("expected 2 neighbors, got %+v", neighbors)
+	}
+	if neighbors[0].MovieID != 1 || !slices.Equal(neighbors[0].PersonIDs, []int{11, 10}) {
+		t.Errorf("expected Start through Star and Lead first, got %+v", neighbors[0])
+	}
+	if neighbors[1].MovieID != 3 || !slices.Equal(neighbors[1].PersonIDs, []int{12}) {
+		t.Errorf("expected Later through Regular second, got %+v", neighbors[1])
+	}
+
+	if neighbors := g.Neighbors(5); len(neighbors) != 0 {
+		t.Errorf("expected no neighbors of Island, got %+v", neighbors)
+	}
+}
+
+func TestConnectors(t *testing.T) {
+	g := newTestGraph()
+
+	if people := g.Connectors(1, 2); !slices.Equal(people, []int{11, 10}) {
+		t.Errorf("expected Star and Lead, got %v", people)
+	}
+	if people := g.Connectors(1, 3); len(people) != 0 {
+		t.Errorf("expected nobody, got %v", people)
+	}
+	if people := g.Connectors(1, 1); len(people) != 0 {
+		t.Errorf("expected nobody connecting a movie to itself, got %v", people)
+	}
+	if jobTypes := g.JobTypes(10, 2); len(jobTypes) != 2 {
+		t.Errorf("expected Lead to act in and direct Middle, got %v", jobTypes)
+	}
+	if movies := g.PersonMovies(14); !slices.Equal(movies, []int{5}) {
+		t.Errorf("expected the unknown movie to be left out, got %v", movies)
+	}
+}
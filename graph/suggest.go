@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"cmp"
+	"container/heap"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Centrality is how Suggest weighs the links a person adds.
+type Centrality string
+
+const (
+	// Every pair of movies a person newly links counts the same
+	CentralityDegree Centrality = "degree"
+	// Pairs of movies more shortest chains pass through count more
+	CentralityBetweenness Centrality = "betweenness"
+)
+
+// SuggestOptions selects the people Suggest picks.
+type SuggestOptions struct {
+	// People already known, whose links don't count
+	Known []int
+	// At most this many people are picked
+	Count      int
+	Centrality Centrality
+	// Movies chains are sampled from for CentralityBetweenness, every movie
+	// when not positive
+	Samples int
+	// Draws the samples, only needed with Samples
+	Rand *rand.Rand
+}
+
+// Suggestion is a person worth learning next.
+type Suggestion struct {
+	PersonID int
+	// Movies of the person in the graph
+	Movies int
+	// Pairs of movies the person links that no one known or picked before
+	// links
+	NewLinks int
+	// NewLinks weighed by the centrality of the movies
+	Gain float64
+	// Degree or estimated betweenness of the person
+	Centrality float64
+}
+
+// Suggest picks the people whose movies add the most links between movies
+// beyond what the known people already link. Each pick counts the links of
+// the picks before it as known, so the picks complement each other rather
+// than all covering the same movies.
+func (g *Graph) Suggest(opts SuggestOptions) ([]Suggestion, error) {
+	weight := func(a, b int) float64 { return 1 }
+	centrality := func(personID int) float64 { return float64(len(g.people[personID].movies)) }
+
+	switch opts.Centrality {
+	case CentralityDegree, "":
+	case CentralityBetweenness:
+		if opts.Samples > 0 && opts.Rand == nil {
+			return nil, fmt.Errorf("sampling movies needs Rand")
+		}
+		movies, people := g.Betweenness(opts.Samples, opts.Rand)
+
+		// Scaled so the average movie weighs 1, like with degree
+		var total float64
+		for _, value := range movies {
+			total += value
+		}
+		mean := total / float64(max(len(movies), 1))
+		if mean > 0 {
+			weight = func(a, b int) float64 { return (movies[a] + movies[b]) / 2 / mean }
+		}
+		centrality = func(personID int) float64 { return people[personID] }
+	default:
+		return nil, fmt.Errorf("unknown centrality %q", opts.Centrality)
+	}
+
+	linked := make(map[[2]int]bool)
+	link := func(personID int) {
+		movies := g.people[personID].movies
+		for i, a := range movies {
+			for _, b := range movies[i+1:] {
+				linked[[2]int{min(a, b), max(a, b)}] = true
+			}
+		}
+	}
+	evaluate := func(candidate *suggestionCandidate) {
+		candidate.NewLinks, candidate.Gain = 0, 0
+		movies := g.people[candidate.PersonID].movies
+		for i, a := range movies {
+			for _, b := range movies[i+1:] {
+				if !linked[[2]int{min(a, b), max(a, b)}] {
+					candidate.NewLinks++
+					candidate.Gain += weight(a, b)
+				}
+			}
+		}
+	}
+
+	known := make(map[int]bool, len(opts.Known))
+	for _, personID := range opts.Known {
+		if _, ok := g.people[personID]; ok {
+			known[personID] = true
+			link(personID)
+		}
+	}
+
+	candidates := &suggestionHeap{graph: g}
+	for id, person := range g.people {
+		if known[id] || len(person.movies) < 2 {
+			continue
+		}
+		candidate := &suggestionCandidate{Suggestion: Suggestion{PersonID: id, Movies: len(person.movies), Centrality: centrality(id)}}
+		evaluate(candidate)
+		candidates.candidates = append(candidates.candidates, candidate)
+	}
+	heap.Init(candidates)
+
+	// Gains only shrink as more pairs are linked, so a candidate whose
+	// gain is still the highest after updating it is the best pick
+	suggestions := []Suggestion{}
+	for candidates.Len() > 0 && len(suggestions) < opts.Count {
+		best := candidates.candidates[0]
+		if best.picks != len(suggestions) {
+			evaluate(best)
+			best.picks = len(suggestions)
+			heap.Fix(candidates, 0)
+			continue
+		}
+		if best.NewLinks == 0 {
+			break
+		}
+
+		heap.Pop(candidates)
+		link(best.PersonID)
+		suggestions = append(suggestions, best.Suggestion)
+	}
+
+	return suggestions, nil
+}
+
+type suggestionCandidate struct {
+	Suggestion
+	// Picks made when the gain was last evaluated
+	picks int
+}
+
+// suggestionHeap orders candidates by gain, then by popularity.
+type suggestionHeap struct {
+	graph      *Graph
+	candidates []*suggestionCandidate
+}
+
+func (h *suggestionHeap) Len() int { return len(h.candidates) }
+
+func (h *suggestionHeap) Less(i, j int) bool {
+	a, b := h.candidates[i], h.candidates[j]
+	return cmp.Or(
+		cmp.Compare(b.Gain, a.Gain),
+		h.graph.comparePeople(a.PersonID, b.PersonID),
+	) < 0
+}
+
+func (h *suggestionHeap) Swap(i, j int) {
+	h.candidates[i], h.candidates[j] = h.candidates[j], h.candidates[i]
+}
+
+func (h *suggestionHeap) Push(x any) {
+	h.candidates = append(h.candidates, x.(*suggestionCandidate))
+}
+
+func (h *suggestionHeap) Pop() any {
+	last := h.candidates[len(h.candidates)-1]
+	h.candidates = h.candidates[:len(h.candidates)-1]
+	return last
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestBetweenness(t *testing.T) {
+	// Start -Star- Middle -Regular- Later -Director- End
+	g := newTestGraph()
+
+	movies, people := g.Betweenness(0, nil)
+
+	// Middle and Later sit on the chains between the movies either side
+	for id, expected := range map[int]float64{1: 0, 2: 2, 3: 2, 4: 0, 5: 0} {
+		if movies[id] != expected {
+			t.Errorf("expected movie %d to have betweenness %g, got %g", id, expected, movies[id])
+		}
+	}
+	// Lead and Star split the chains from Start
+	for id, expected := range map[int]float64{10: 1.5, 11: 1.5, 12: 4, 13: 3, 14: 0} {
+		if people[id] != expected {
+			t.Errorf("expected person %d to have betweenness %g, got %g", id, expected, people[id])
+		}
+	}
+
+	sampled, _ := g.Betweenness(2, rand.New(rand.NewPCG(1, 2)))
+	if len(sampled) != len(movies) {
+		t.Errorf("expected a betweenness for every movie, got %v", sampled)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	g := newTestGraph()
+
+	for _, centrality := range []Centrality{CentralityDegree, CentralityBetweenness} {
+		t.Run(string(centrality), func(t *testing.T) {
+			suggestions, err := g.Suggest(SuggestOptions{Known: []int{11}, Count: 5, Centrality: centrality})
+			if err != nil {
+				t.Fatalf("Suggest failed: %v", err)
+			}
+
+			// Lead only links Start and Middle, which Star already links
+			var ids []int
+			for _, suggestion := range suggestions {
+				ids = append(ids, suggestion.PersonID)
+				if suggestion.NewLinks != 1 || suggestion.Movies != 2 {
+					t.Errorf("expected one new link out of two movies, got %+v", suggestion)
+				}
+			}
+			if len(ids) != 2 || ids[0] != 12 || ids[1] != 13 {
+				t.Errorf("expected Regular then Director, got %v", ids)
+			}
+		})
+	}
+
+	if suggestions, err := g.Suggest(SuggestOptions{Count: 1}); err != nil || len(suggestions) != 1 || suggestions[0].PersonID != 11 {
+		t.Errorf("expected the most popular of the tied people, got %+v %v", suggestions, err)
+	}
+	if _, err := g.Suggest(SuggestOptions{Count: 1, Centrality: "closeness"}); err == nil {
+		t.Errorf("expected an unknown centrality to fail")
+	}
+}
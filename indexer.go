@@ -0,0 +1,407 @@
+package tmdbankigenerator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"gitlab.com/metakeule/fmtdate"
+	"golang.org/x/sync/errgroup"
+)
+
+type IndexOptions struct {
+	// Movies and people below this TMDB popularity are skipped
+	MinPopularity float32
+	// Movies with fewer TMDB votes than this are skipped
+	MinVoteCount int
+	// Number of crawled movies written to the database at a time
+	BatchSize int
+	// Number of movies fetched from TMDB in parallel
+	Concurrency int
+}
+
+type IndexStats struct {
+	Total          int
+	AlreadyCrawled int
+	Indexed        int
+	Skipped        int
+	People         int
+	Credits        int
+}
+
+// Indexer crawls movies with their people and credits from TMDB into the
+// database. Progress is checkpointed in batches, so an interrupted crawl
+// continues where it left off the next time.
+type Indexer struct {
+	db   *Database
+	tmdb *TMDbClient
+	opts IndexOptions
+
+	// Aliases of the people fetched so far, each person is fetched once
+	mu      sync.Mutex
+	aliases map[int]*aliasFetch
+}
+
+// aliasFetch is the aliases of a person, available once done is closed.
+type aliasFetch struct {
+	done    chan struct{}
+	aliases []string
+	err     error
+}
+
+func NewIndexer(db *Database, tmdb *TMDbClient, opts IndexOptions) *Indexer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 30
+	}
+
+	return &Indexer{
+		db:      db,
+		tmdb:    tmdb,
+		opts:    opts,
+		aliases: make(map[int]*aliasFetch),
+	}
+}
+
+// crawlResult is what crawling a single movie produced. Movie is nil when the
+// movie was skipped.
+type crawlResult struct {
+	MovieID int
+	Movie   *Movie
+	People  []Person
+	Credits []Credit
+}
+
+// Index crawls the movies that haven't been crawled before. When ctx is
+// cancelled, the movies crawled so far are still written to the database.
+func (ix *Indexer) Index(ctx context.Context, movies []PopularMovie) (IndexStats, error) {
+	stats := IndexStats{Total: len(movies)}
+
+	crawled, err := ix.db.GetCrawledMovieIDs()
+	if err != nil {
+		return stats, err
+	}
+
+	pending := make([]PopularMovie, 0, len(movies))
+	for _, movie := range movies {
+		if _, ok := crawled[movie.ID]; ok {
+			stats.AlreadyCrawled++
+			continue
+		}
+		pending = append(pending, movie)
+	}
+
+	if stats.AlreadyCrawled > 0 {
+		fmt.Printf("Resuming, %d of %d movies were already crawled\n", stats.AlreadyCrawled, stats.Total)
+	}
+
+	crawlCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan crawlResult, ix.opts.BatchSize)
+	written := make(chan error, 1)
+	go func() {
+		written <- ix.writeResults(results, cancel, &stats)
+	}()
+
+	g, gctx := errgroup.WithContext(crawlCtx)
+	g.SetLimit(ix.opts.Concurrency)
+
+	for _, movie := range pending {
+		if gctx.Err() != nil {
+			break
+		}
+
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+
+			result, err := ix.crawlMovie(gctx, movie.ID)
+			if err != nil {
+				return fmt.Errorf("failed to crawl movie %d: %w", movie.ID, err)
+			}
+
+			results <- result
+			return nil
+		})
+	}
+
+	crawlErr := g.Wait()
+	close(results)
+
+	if err := <-written; err != nil {
+		return stats, err
+	}
+	if ctx.Err() != nil {
+		return stats, fmt.Errorf("interrupted, %d movies left to crawl: %w", stats.Total-stats.AlreadyCrawled-stats.Indexed-stats.Skipped, ctx.Err())
+	}
+	if crawlErr != nil && !errors.Is(crawlErr, context.Canceled) {
+		return stats, crawlErr
+	}
+
+	return stats, nil
+}
+
+// writeResults writes the crawled movies in batches until results is closed.
+// If writing fails the crawl is cancelled, but results are still drained so
+// no crawler blocks.
+func (ix *Indexer) writeResults(results <-chan crawlResult, cancel func(), stats *IndexStats) error {
+	var batch []crawlResult
+	var writeErr error
+
+	flush := func() {
+		if writeErr == nil && len(batch) > 0 {
+			if err := ix.writeBatch(batch, stats); err != nil {
+				writeErr = err
+				cancel()
+			}
+		}
+		batch = batch[:0]
+	}
+
+	for result := range results {
+		batch = append(batch, result)
+		if len(batch) >= ix.opts.BatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	return writeErr
+}
+
+func (ix *Indexer) writeBatch(batch []crawlResult, stats *IndexStats) error {
+	var (
+		movies   []Movie
+		people   []Person
+		credits  []Credit
+		statuses = make(map[int]CrawlStatus, len(batch))
+		seen     = make(map[int]bool)
+	)
+
+	for _, result := range batch {
+		if result.Movie == nil {
+			statuses[result.MovieID] = CrawlStatusSkipped
+			continue
+		}
+
+		statuses[result.MovieID] = CrawlStatusIndexed
+		movies = append(movies, *result.Movie)
+		credits = append(credits, result.Credits...)
+		for _, person := range result.People {
+			if !seen[person.ID] {
+				seen[person.ID] = true
+				people = append(people, person)
+			}
+		}
+	}
+
+	if err := ix.db.UpsertMovies(movies); err != nil {
+		return fmt.Errorf("failed to insert movies: %w", err)
+	}
+	if err := ix.db.UpsertPeople(people); err != nil {
+		return fmt.Errorf("failed to insert people: %w", err)
+	}
+	if err := ix.db.UpsertCredits(credits); err != nil {
+		return fmt.Errorf("failed to insert credits: %w", err)
+	}
+	if err := ix.db.MarkMoviesCrawled(statuses); err != nil {
+		return err
+	}
+
+	stats.Indexed += len(movies)
+	stats.Skipped += len(batch) - len(movies)
+	stats.People += len(people)
+	stats.Credits += len(credits)
+
+	done := stats.AlreadyCrawled + stats.Indexed + stats.Skipped
+	fmt.Printf("%d%% done (%d/%d movies)\n", done*100/stats.Total, done, stats.Total)
+
+	return nil
+}
+
+func (ix *Indexer) crawlMovie(ctx context.Context, movieID int) (crawlResult, error) {
+	result := crawlResult{MovieID: movieID}
+
+	tmdbMovie, err := ix.tmdb.GetMovieDetails(ctx, movieID, map[string]string{
+		"append_to_response": "keywords,credits",
+	})
+	if IsTMDbNotFound(err) {
+		// Deleted from TMDB since the export was made
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if tmdbMovie.VoteCount < int64(ix.opts.MinVoteCount) ||
+		IsDisallowedGenre(tmdbMovie.Genres) ||
+		IsShortFilm(*tmdbMovie.Keywords.MovieKeywords, tmdbMovie.Runtime) ||
+		!slices.Contains(ValidLanguages, Language(tmdbMovie.OriginalLanguage)) {
+		return result, nil
+	}
+
+	releaseDate, err := fmtdate.Parse("YYYY-MM-DD", tmdbMovie.ReleaseDate)
+	if err != nil {
+		// Skipped rather than failed, or resuming would get stuck on it
+		fmt.Printf("Skipping %s: invalid release date %q\n", tmdbMovie.Title, tmdbMovie.ReleaseDate)
+		return result, nil
+	}
+
+	result.Movie = &Movie{
+		ID:            int(tmdbMovie.ID),
+		Title:         tmdbMovie.Title,
+		OriginalTitle: tmdbMovie.OriginalTitle,
+		Popularity:    tmdbMovie.Popularity,
+		ReleaseDate:   releaseDate,
+		Adult:         tmdbMovie.Adult,
+		Language:      Language(tmdbMovie.OriginalLanguage),
+		Runtime:       tmdbMovie.Runtime,
+		VoteCount:     int(tmdbMovie.VoteCount),
+		VoteAverage:   tmdbMovie.VoteAverage,
+		IMDbID:        tmdbMovie.IMDbID,
+		Tagline:       tmdbMovie.Tagline,
+		Overview:      tmdbMovie.Overview,
+		Budget:        tmdbMovie.Budget,
+		Revenue:       tmdbMovie.Revenue,
+		Genres:        make([]Genre, len(tmdbMovie.Genres)),
+		Images: []MovieImage{
+			{
+				MovieID: int(tmdbMovie.ID),
+				Path:    tmdbMovie.PosterPath,
+			},
+		},
+	}
+
+	for i, genre := range tmdbMovie.Genres {
+		result.Movie.Genres[i] = Genre{ID: int(genre.ID), Name: genre.Name}
+	}
+	if collection := tmdbMovie.BelongsToCollection; collection.ID != 0 {
+		result.Movie.Collection = &Collection{ID: int(collection.ID), Name: collection.Name, PosterPath: collection.PosterPath}
+	}
+	for _, company := range tmdbMovie.ProductionCompanies {
+		result.Movie.Companies = append(result.Movie.Companies, Company{ID: int(company.ID), Name: company.Name, OriginCountry: company.OriginCountry})
+	}
+	for _, country := range tmdbMovie.ProductionCountries {
+		result.Movie.Countries = append(result.Movie.Countries, Country{Code: country.Iso3166_1, Name: country.Name})
+	}
+
+	addPerson := func(id int64, name, department, profilePath string, popularity float32, gender int, adult bool, credit Credit) {
+		result.People = append(result.People, Person{
+			ID:                 int(id),
+			KnownForDepartment: department,
+			Name:               name,
+			Popularity:         popularity,
+			Gender:             gender,
+			ProfilePath:        profilePath,
+			Adult:              adult,
+			Images: []PersonImage{
+				{
+					PersonID: int(id),
+					Path:     profilePath,
+				},
+			},
+		})
+
+		credit.PersonID = int(id)
+		credit.MovieID = int(tmdbMovie.ID)
+		result.Credits = append(result.Credits, credit)
+	}
+
+	// TMDB lists people playing several characters once per character, but
+	// a person has one cast credit per movie
+	castCredits := map[int64]int{}
+	for _, person := range tmdbMovie.Credits.MovieCredits.Cast {
+		if person.Popularity < ix.opts.MinPopularity {
+			continue
+		}
+
+		if i, ok := castCredits[person.ID]; ok {
+			credit := &result.Credits[i]
+			if person.Character != "" {
+				credit.Character = strings.TrimPrefix(credit.Character+" / "+person.Character, " / ")
+			}
+			continue
+		}
+
+		addPerson(person.ID, person.Name, person.KnownForDepartment, person.ProfilePath, person.Popularity, person.Gender, person.Adult, Credit{
+			JobType:   JobTypeCast,
+			CastOrder: person.Order,
+			Character: person.Character,
+			CreditID:  person.CreditID,
+		})
+		castCredits[person.ID] = len(result.Credits) - 1
+	}
+
+	for _, person := range tmdbMovie.Credits.MovieCredits.Crew {
+		if person.Popularity < ix.opts.MinPopularity {
+			continue
+		}
+
+		jobType := JobType(strings.Trim(person.Job, " "))
+		if !jobType.IsValid() {
+			fmt.Printf("Invalid job type on movie %s: %s\n", tmdbMovie.Title, jobType)
+			continue
+		}
+
+		addPerson(person.ID, person.Name, person.KnownForDepartment, person.ProfilePath, person.Popularity, person.Gender, person.Adult, Credit{
+			JobType:    jobType,
+			CreditID:   person.CreditID,
+			Department: person.Department,
+			Job:        person.Job,
+		})
+	}
+
+	// The credits don't include the other names people are known by, which
+	// resolving cast names matches too
+	for i, person := range result.People {
+		aliases, err := ix.personAliases(ctx, person.ID)
+		if err != nil {
+			return result, fmt.Errorf("failed to fetch the aliases of %s: %w", person.Name, err)
+		}
+		result.People[i].AlsoKnownAs = aliases
+	}
+
+	return result, nil
+}
+
+// personAliases fetches the aliases of a person once per indexer, crawlers
+// asking at the same time wait for the same request. They're nil for people
+// TMDB no longer knows, which keeps the stored ones.
+func (ix *Indexer) personAliases(ctx context.Context, personID int) ([]string, error) {
+	ix.mu.Lock()
+	fetch, ok := ix.aliases[personID]
+	if !ok {
+		fetch = &aliasFetch{done: make(chan struct{})}
+		ix.aliases[personID] = fetch
+	}
+	ix.mu.Unlock()
+
+	if ok {
+		select {
+		case <-fetch.done:
+			return fetch.aliases, fetch.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	fetch.aliases, fetch.err = ix.tmdb.GetPersonAliases(ctx, personID)
+	if IsTMDbNotFound(fetch.err) {
+		fetch.aliases, fetch.err = nil, nil
+	}
+	if fetch.err != nil {
+		// Fetched again by the next crawl rather than failing it too
+		ix.mu.Lock()
+		delete(ix.aliases, personID)
+		ix.mu.Unlock()
+	}
+	close(fetch.done)
+	return fetch.aliases, fetch.err
+}
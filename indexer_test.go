@@ -0,0 +1,291 @@
+package tmdbankigenerator
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonasRothmann/cine2nerdle-trainer/tmdbtest"
+)
+
+var (
+	bradPitt     = tmdbtest.CastMember{ID: 287, Name: "Brad Pitt", Character: "Tyler Durden", Popularity: 50, KnownForDepartment: "Acting", ProfilePath: "/pitt.jpg"}
+	edwardNorton = tmdbtest.CastMember{ID: 819, Name: "Edward Norton", Character: "The Narrator", Order: 1, Popularity: 30, KnownForDepartment: "Acting", ProfilePath: "/norton.jpg"}
+	extra        = tmdbtest.CastMember{ID: 9999, Name: "Unknown Extra", Character: "Waiter", Order: 2, Popularity: 0.1}
+	davidFincher = tmdbtest.CrewMember{ID: 7467, Name: "David Fincher", Job: "Director", Department: "Directing", Popularity: 20, KnownForDepartment: "Directing", ProfilePath: "/fincher.jpg"}
+)
+
+// testMovies are the fixtures of the fake TMDB server, one for each reason
+// the indexer keeps or skips a movie.
+var testMovies = []tmdbtest.Movie{
+	{
+		ID: 550, Title: "Fight Club", OriginalLanguage: "en", ReleaseDate: "1999-10-15", Runtime: 139,
+		Popularity: 60, VoteCount: 30000, PosterPath: "/fightclub.jpg", Genres: []tmdbtest.Genre{tmdbtest.GenreDrama},
+		OriginalTitle: "Fight Club", Tagline: "Mischief. Mayhem. Soap.", IMDbID: "tt0137523", VoteAverage: 8.4, Budget: 63000000, Revenue: 100853753,
+		ProductionCompanies: []tmdbtest.Company{{ID: 711, Name: "Fox 2000 Pictures", OriginCountry: "US"}, {ID: 508, Name: "Regency Enterprises", OriginCountry: "US"}},
+		ProductionCountries: []tmdbtest.Country{{Code: "DE", Name: "Germany"}, {Code: "US", Name: "United States of America"}},
+		Cast:                []tmdbtest.CastMember{bradPitt, edwardNorton, extra},
+		Crew:                []tmdbtest.CrewMember{davidFincher, {ID: 1, Name: "Best Boy", Job: "Best Boy Electric", Popularity: 5}},
+	},
+	{
+		ID: 807, Title: "Se7en", OriginalLanguage: "en", ReleaseDate: "1995-09-22", Runtime: 127,
+		Popularity: 50, VoteCount: 20000, PosterPath: "/se7en.jpg", Genres: []tmdbtest.Genre{tmdbtest.GenreThriller},
+		BelongsToCollection: &tmdbtest.Collection{ID: 1000, Name: "Seven Collection", PosterPath: "/seven-collection.jpg"},
+		Cast:                []tmdbtest.CastMember{bradPitt},
+		Crew:                []tmdbtest.CrewMember{davidFincher},
+	},
+	{
+		ID: 1001, Title: "Too Short", OriginalLanguage: "en", ReleaseDate: "2010-01-01", Runtime: 20,
+		Popularity: 10, VoteCount: 100, Cast: []tmdbtest.CastMember{bradPitt},
+	},
+	{
+		ID: 1002, Title: "Tagged Short", OriginalLanguage: "en", ReleaseDate: "2010-01-01", Runtime: 90,
+		Popularity: 10, VoteCount: 100, Keywords: []string{"Short Film"}, Cast: []tmdbtest.CastMember{bradPitt},
+	},
+	{
+		ID: 1003, Title: "A Documentary", OriginalLanguage: "en", ReleaseDate: "2010-01-01", Runtime: 90,
+		Popularity: 10, VoteCount: 100, Genres: []tmdbtest.Genre{tmdbtest.GenreDocumentary}, Cast: []tmdbtest.CastMember{bradPitt},
+	},
+	{
+		ID: 1004, Title: "A Concert", OriginalLanguage: "en", ReleaseDate: "2010-01-01", Runtime: 90,
+		Popularity: 10, VoteCount: 100, Genres: []tmdbtest.Genre{tmdbtest.GenreMusic}, Cast: []tmdbtest.CastMember{bradPitt},
+	},
+	{
+		ID: 1005, Title: "Few Votes", OriginalLanguage: "en", ReleaseDate: "2010-01-01", Runtime: 90,
+		Popularity: 10, VoteCount: 3, Cast: []tmdbtest.CastMember{bradPitt},
+	},
+	{
+		ID: 1006, Title: "Le Film", OriginalLanguage: "fr", ReleaseDate: "2010-01-01", Runtime: 90,
+		Popularity: 10, VoteCount: 100, Cast: []tmdbtest.CastMember{bradPitt},
+	},
+}
+
+func newTestIndexer(t *testing.T) (*Indexer, *Database, *tmdbtest.Server) {
+	t.Helper()
+
+	server := tmdbtest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddMovies(testMovies...)
+
+	tmdb, err := NewTMDbClient("test", server.URL)
+	if err != nil {
+		t.Fatalf("NewTMDbClient failed: %v", err)
+	}
+
+	db, err := NewDatabase(DatabaseOptions{InMemory: true})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return NewIndexer(db, tmdb, IndexOptions{MinPopularity: 1, MinVoteCount: 10, BatchSize: 3, Concurrency: 4}), db, server
+}
+
+func TestIndexer(t *testing.T) {
+	indexer, db, server := newTestIndexer(t)
+
+	// Deleted from TMDB since the export was made
+	popular := []PopularMovie{{ID: 404}}
+	for _, movie := range testMovies {
+		popular = append(popular, PopularMovie{ID: int(movie.ID), Popularity: movie.Popularity})
+	}
+
+	server.FailNext("/movie/807", http.StatusTooManyRequests, http.StatusInternalServerError)
+
+	stats, err := indexer.Index(context.Background(), popular)
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+
+	if stats.Indexed != 2 || stats.Skipped != len(popular)-2 {
+		t.Errorf("expected 2 indexed and %d skipped movies, got %+v", len(popular)-2, stats)
+	}
+	if stats.Credits != 5 {
+		t.Errorf("expected 5 credits, got %d", stats.Credits)
+	}
+	if got := server.Requests("/movie/807"); got != 3 {
+		t.Errorf("expected Se7en to be retried twice, got %d requests", got)
+	}
+
+	movies, err := db.GetMoviesByPersonIDs([]int{int(bradPitt.ID)}, []int{}, 0)
+	if err != nil {
+		t.Fatalf("GetMoviesByPersonIDs failed: %v", err)
+	}
+
+	titles := map[string]bool{}
+	for _, movie := range movies {
+		titles[movie.Title] = true
+		if movie.Title == "Fight Club" && (len(movie.Genres) != 1 || movie.Genres[0].Name != "Drama") {
+			t.Errorf("expected Fight Club to be a Drama, got %v", movie.Genres)
+		}
+		if movie.Title == "Fight Club" && (movie.IMDbID != "tt0137523" || movie.Budget != 63000000 || movie.VoteCount != 30000 ||
+			len(movie.Companies) != 2 || len(movie.Countries) != 2 || movie.Collection != nil) {
+			t.Errorf("expected the metadata of Fight Club to be stored, got %+v", movie)
+		}
+		if movie.Title == "Se7en" && (movie.Collection == nil || movie.Collection.Name != "Seven Collection") {
+			t.Errorf("expected Se7en to be part of a collection, got %+v", movie.Collection)
+		}
+		for _, person := range movie.Persons {
+			if person.ID == int(extra.ID) {
+				t.Errorf("expected unpopular %s to be left out of %s", person.Name, movie.Title)
+			}
+			if movie.Title == "Fight Club" && person.ID == int(edwardNorton.ID) && (person.CastOrder != 1 || person.Character != "The Narrator") {
+				t.Errorf("expected Edward Norton billed second as The Narrator, got %d %q", person.CastOrder, person.Character)
+			}
+		}
+	}
+	if len(titles) != 2 || !titles["Fight Club"] || !titles["Se7en"] {
+		t.Errorf("expected Fight Club and Se7en, got %v", titles)
+	}
+
+	crawled, err := db.GetCrawledMovieIDs()
+	if err != nil {
+		t.Fatalf("GetCrawledMovieIDs failed: %v", err)
+	}
+	if len(crawled) != len(popular) || crawled[550] != CrawlStatusIndexed || crawled[1002] != CrawlStatusSkipped {
+		t.Errorf("unexpected crawl state: %v", crawled)
+	}
+
+	t.Run("Resume", func(t *testing.T) {
+		requests := server.TotalRequests()
+
+		stats, err := indexer.Index(context.Background(), popular)
+		if err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+		if stats.AlreadyCrawled != len(popular) || stats.Indexed != 0 {
+			t.Errorf("expected every movie to be crawled already, got %+v", stats)
+		}
+		if got := server.TotalRequests(); got != requests {
+			t.Errorf("expected no requests when resuming a finished crawl, got %d", got-requests)
+		}
+	})
+}
+
+func TestIndexerInterrupted(t *testing.T) {
+	indexer, db, server := newTestIndexer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	popular := []PopularMovie{{ID: 550}, {ID: 807}}
+	if _, err := indexer.Index(ctx, popular); err == nil {
+		t.Fatalf("expected an interrupted crawl to fail")
+	}
+
+	crawled, err := db.GetCrawledMovieIDs()
+	if err != nil {
+		t.Fatalf("GetCrawledMovieIDs failed: %v", err)
+	}
+	if len(crawled) != 0 {
+		t.Errorf("expected nothing to be crawled, got %v", crawled)
+	}
+
+	stats, err := indexer.Index(context.Background(), popular)
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if stats.Indexed != 2 {
+		t.Errorf("expected both movies to be crawled after resuming, got %+v", stats)
+	}
+	if server.Requests("/movie/550") != 1 {
+		t.Errorf("expected Fight Club to be fetched once, got %d", server.Requests("/movie/550"))
+	}
+}
+
+func TestGetAllCreditsByPersonID(t *testing.T) {
+	_, _, server := newTestIndexer(t)
+	server.AddPeople(tmdbtest.Person{ID: bradPitt.ID, Name: bradPitt.Name, Popularity: bradPitt.Popularity, ProfilePath: bradPitt.ProfilePath})
+
+	tmdb, err := NewTMDbClient("test", server.URL)
+	if err != nil {
+		t.Fatalf("NewTMDbClient failed: %v", err)
+	}
+
+	person, credits, err := tmdb.GetAllCreditsByPersonID(context.Background(), int(bradPitt.ID))
+	if err != nil {
+		t.Fatalf("GetAllCreditsByPersonID failed: %v", err)
+	}
+	if person.Name != "Brad Pitt" {
+		t.Errorf("expected Brad Pitt, got %q", person.Name)
+	}
+
+	movieIDs := map[int]bool{}
+	for _, credit := range credits {
+		movieIDs[credit.MovieID] = true
+	}
+	// The french movie isn't filtered by language here, only the indexer
+	// filters on language
+	for _, id := range []int{550, 807, 1006} {
+		if !movieIDs[id] {
+			t.Errorf("expected a credit on movie %d, got %v", id, movieIDs)
+		}
+	}
+	for _, id := range []int{1001, 1002, 1003, 1004, 1005} {
+		if movieIDs[id] {
+			t.Errorf("expected movie %d to be filtered out", id)
+		}
+	}
+
+	if _, _, err := tmdb.GetAllCreditsByPersonID(context.Background(), 1); !IsTMDbNotFound(err) {
+		t.Errorf("expected a not found error for an unknown person, got %v", err)
+	}
+}
+
+func TestIndexerAliases(t *testing.T) {
+	indexer, db, server := newTestIndexer(t)
+	server.AddPeople(tmdbtest.Person{ID: bradPitt.ID, Name: bradPitt.Name, AlsoKnownAs: []string{"William Bradley Pitt", "브래드 피트"}})
+
+	if _, err := indexer.Index(context.Background(), []PopularMovie{{ID: 550}, {ID: 807}}); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if got := server.Requests("/person/287"); got != 1 {
+		t.Errorf("expected the aliases of Brad Pitt to be fetched once, got %d requests", got)
+	}
+
+	// The export only has names, the aliases come from the database
+	export := filepath.Join(t.TempDir(), "person_ids.json")
+	lines := `{"adult":false,"id":287,"name":"Brad Pitt","popularity":50}` + "\n" +
+		`{"adult":false,"id":819,"name":"Edward Norton","popularity":30}` + "\n"
+	if err := os.WriteFile(export, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := LoadPersonCandidates(export, db)
+	if err != nil {
+		t.Fatalf("LoadPersonCandidates failed: %v", err)
+	}
+	resolver := NewPersonResolver(candidates)
+
+	for _, name := range []string{"William Bradley Pitt", "브래드 피트"} {
+		resolution, err := resolver.Resolve(name)
+		if err != nil {
+			t.Fatalf("Resolve(%q) failed: %v", name, err)
+		}
+		if resolution.Person.ID != int(bradPitt.ID) || resolution.Match != "also known as" {
+			t.Errorf("expected %q to resolve to Brad Pitt by also known as, got id %d by %s", name, resolution.Person.ID, resolution.Match)
+		}
+	}
+
+	// Edward Norton isn't known to the person endpoint, so he has no aliases
+	// rather than failing the crawl
+	for _, candidate := range candidates {
+		if candidate.ID == int(edwardNorton.ID) && len(candidate.AlsoKnownAs) != 0 {
+			t.Errorf("expected no aliases for Edward Norton, got %v", candidate.AlsoKnownAs)
+		}
+	}
+
+	t.Run("Search", func(t *testing.T) {
+		skipWithoutSearch(t, db)
+
+		results, err := db.SearchPeople("william bradley", 0)
+		if err != nil {
+			t.Fatalf("SearchPeople failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != int(bradPitt.ID) || results[0].Match != SearchMatchAlias {
+			t.Errorf("expected to find Brad Pitt by an alias, got %+v", results)
+		}
+	})
+}
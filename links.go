@@ -0,0 +1,125 @@
+package tmdbankigenerator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LinkQuery selects the credits that link movies through the people they
+// share, as Cine2Nerdle chains them.
+type LinkQuery struct {
+	// Only credits of these job types link movies, every job type when empty
+	JobTypes []JobType
+	// Movies less popular than this are left out
+	MinMoviePopularity float32
+	IncludeAdult       bool
+}
+
+func (q LinkQuery) validate() error {
+	for _, jobType := range q.JobTypes {
+		if !jobType.IsValid() {
+			return fmt.Errorf("invalid job type %q", jobType)
+		}
+	}
+	return nil
+}
+
+// Links are the movies, people and credits selected by a LinkQuery. Movies
+// only have their ID, title, popularity and release date, people their ID,
+// name and popularity. Only people with a selected credit are included.
+type Links struct {
+	Movies  []Movie
+	People  []Person
+	Credits []Credit
+}
+
+// Links returns the movies, people and credits selected by query, ordered
+// by ID.
+func (d *Database) Links(query LinkQuery) (*Links, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	movieWhere := []string{"COALESCE(m.popularity, 0) >= ?"}
+	movieArgs := []any{query.MinMoviePopularity}
+	if !query.IncludeAdult {
+		movieWhere = append(movieWhere, "COALESCE(m.adult, 0) = 0")
+	}
+
+	creditWhere := slices.Clone(movieWhere)
+	creditArgs := slices.Clone(movieArgs)
+	if len(query.JobTypes) > 0 {
+		creditWhere = append(creditWhere, "c.job_type IN (?)")
+		creditArgs = append(creditArgs, query.JobTypes)
+	}
+
+	links := &Links{}
+
+	rows, err := d.conn.Queryx(`
+    SELECT m.id, COALESCE(m.title, ''), COALESCE(m.popularity, 0), COALESCE(m.release_date, '')
+    FROM movies m
+    WHERE `+strings.Join(movieWhere, " AND ")+`
+    ORDER BY m.id
+    `, movieArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movie Movie
+		var releaseDate string
+		if err := rows.Scan(&movie.ID, &movie.Title, &movie.Popularity, &releaseDate); err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %w", err)
+		}
+		if movie.ReleaseDate, err = parseReleaseDate(releaseDate); err != nil {
+			return nil, fmt.Errorf("movie %d: %w", movie.ID, err)
+		}
+		links.Movies = append(links.Movies, movie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	rows.Close()
+
+	err = d.selectIn(&links.Credits, `
+    SELECT DISTINCT c.person_id, c.movie_id, c.job_type
+    FROM credits c
+        INNER JOIN movies m ON m.id = c.movie_id
+        INNER JOIN persons p ON p.id = c.person_id
+    WHERE `+strings.Join(creditWhere, " AND ")+`
+    ORDER BY c.person_id, c.movie_id, c.job_type
+    `, creditArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query credits: %w", err)
+	}
+
+	err = d.selectIn(&links.People, `
+    SELECT p.id, COALESCE(p.name, '') AS name, COALESCE(p.popularity, 0) AS popularity
+    FROM persons p
+    WHERE p.id IN (
+        SELECT c.person_id
+        FROM credits c
+            INNER JOIN movies m ON m.id = c.movie_id
+        WHERE `+strings.Join(creditWhere, " AND ")+`
+    )
+    ORDER BY p.id
+    `, creditArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query people: %w", err)
+	}
+
+	return links, nil
+}
+
+// selectIn is Select for queries with slices to expand into IN (?).
+func (d *Database) selectIn(dest any, query string, args ...any) error {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to construct query: %w", err)
+	}
+	return d.conn.Select(dest, d.conn.Rebind(query), args...)
+}
@@ -0,0 +1,54 @@
+package tmdbankigenerator
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	db := newTestQueryDatabase(t)
+
+	tests := []struct {
+		name    string
+		query   LinkQuery
+		movies  []int
+		people  []int
+		credits int
+	}{
+		{"Default", LinkQuery{}, []int{1, 2, 4}, []int{10, 11, 12, 13}, 7},
+		{"Adult", LinkQuery{IncludeAdult: true}, []int{1, 2, 3, 4}, []int{10, 11, 12, 13}, 8},
+		{"Cast only", LinkQuery{JobTypes: []JobType{JobTypeCast}}, []int{1, 2, 4}, []int{10, 11, 12, 13}, 5},
+		{"Directors only", LinkQuery{JobTypes: []JobType{JobTypeDirector}}, []int{1, 2, 4}, []int{10}, 2},
+		{"Popular movies", LinkQuery{MinMoviePopularity: 25}, []int{2, 4}, []int{10, 11}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := db.Links(tt.query)
+			if err != nil {
+				t.Fatalf("Links failed: %v", err)
+			}
+
+			var movies, people []int
+			for _, movie := range links.Movies {
+				movies = append(movies, movie.ID)
+			}
+			for _, person := range links.People {
+				people = append(people, person.ID)
+			}
+			if !slices.Equal(movies, tt.movies) {
+				t.Errorf("expected movies %v, got %v", tt.movies, movies)
+			}
+			if !slices.Equal(people, tt.people) {
+				t.Errorf("expected people %v, got %v", tt.people, people)
+			}
+			if len(links.Credits) != tt.credits {
+				t.Errorf("expected %d credits, got %+v", tt.credits, links.Credits)
+			}
+		})
+	}
+
+	if _, err := db.Links(LinkQuery{JobTypes: []JobType{"Grip"}}); err == nil {
+		t.Errorf("expected an unknown job type to fail")
+	}
+}
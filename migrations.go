@@ -0,0 +1,374 @@
+package tmdbankigenerator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration changes the schema from the previous version to Version. The
+// version a database is at is kept in PRAGMA user_version, 0 being a
+// database from before migrations.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	// Run after SQL, for changes SQL can't express
+	Func func(tx *sqlx.Tx) error
+}
+
+// migrations must be ordered and numbered from 1 without gaps. Never change
+// a migration that was released, add a new one.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		SQL: `
+CREATE TABLE IF NOT EXISTS persons (
+    id                  INTEGER PRIMARY KEY,
+    birthday            TEXT,
+    known_for_department TEXT,
+    name                TEXT,
+    also_known_as       TEXT,    -- JSON array for multiple names
+    gender              INTEGER,
+    popularity          REAL,
+    place_of_birth      TEXT,
+    profile_path        TEXT,
+    adult               BOOLEAN,
+    imdb_id             TEXT
+);
+
+CREATE TABLE IF NOT EXISTS movies (
+    id       		INTEGER PRIMARY KEY,
+    title    		TEXT,
+    language 		TEXT,
+    popularity 		FLOAT,
+    runtime  		FLOAT,
+    genres			TEXT,
+    release_date    TEXT,
+    adult 			BOOLEAN
+);
+
+CREATE TABLE IF NOT EXISTS credits (
+    person_id   INTEGER NOT NULL,
+    movie_id    INTEGER NOT NULL,
+    job_type    TEXT NOT NULL,
+
+    FOREIGN KEY(person_id) REFERENCES persons(id),
+    FOREIGN KEY(movie_id) REFERENCES movies(id),
+    PRIMARY KEY(person_id, movie_id, job_type)
+);
+
+CREATE TABLE IF NOT EXISTS person_images (
+    person_id   INTEGER NOT NULL,
+    path        TEXT NOT NULL,
+
+    FOREIGN KEY(person_id) REFERENCES persons(id),
+    PRIMARY KEY(person_id, path)
+);
+
+CREATE TABLE IF NOT EXISTS movie_images (
+    movie_id   INTEGER NOT NULL,
+    path       TEXT NOT NULL,
+
+    FOREIGN KEY(movie_id) REFERENCES movies(id),
+    PRIMARY KEY(movie_id, path)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credits ON credits(person_id, movie_id, job_type);
+`,
+		// Databases from before migrations, like the data.db that was
+		// shipped, may have the tables but miss columns added later
+		Func: func(tx *sqlx.Tx) error {
+			if err := addMissingColumns(tx, "persons", [][2]string{
+				{"birthday", "TEXT"},
+				{"known_for_department", "TEXT"},
+				{"name", "TEXT"},
+				{"also_known_as", "TEXT"},
+				{"gender", "INTEGER"},
+				{"popularity", "REAL"},
+				{"place_of_birth", "TEXT"},
+				{"profile_path", "TEXT"},
+				{"adult", "BOOLEAN"},
+				{"imdb_id", "TEXT"},
+			}); err != nil {
+				return err
+			}
+
+			return addMissingColumns(tx, "movies", [][2]string{
+				{"title", "TEXT"},
+				{"language", "TEXT"},
+				{"popularity", "FLOAT"},
+				{"runtime", "FLOAT"},
+				{"genres", "TEXT"},
+				{"release_date", "TEXT"},
+				{"adult", "BOOLEAN"},
+			})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "crawl state and metadata",
+		SQL: `
+CREATE TABLE IF NOT EXISTS crawl_state (
+    movie_id    INTEGER PRIMARY KEY,
+    status      TEXT NOT NULL,
+    crawled_at  TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS metadata (
+    key     TEXT PRIMARY KEY,
+    value   TEXT NOT NULL
+);
+`,
+	},
+	{
+		Version: 3,
+		Name:    "normalized genres",
+		SQL: `
+CREATE TABLE genres (
+    id      INTEGER PRIMARY KEY,
+    name    TEXT NOT NULL
+);
+
+CREATE TABLE movie_genres (
+    movie_id    INTEGER NOT NULL,
+    genre_id    INTEGER NOT NULL,
+
+    FOREIGN KEY(movie_id) REFERENCES movies(id),
+    FOREIGN KEY(genre_id) REFERENCES genres(id),
+    PRIMARY KEY(movie_id, genre_id)
+);
+
+CREATE INDEX idx_movie_genres_genre ON movie_genres(genre_id);
+`,
+		// movies.genres held comma separated names, which were never
+		// written by the indexer but may have been set by hand. The column is
+		// left in place, unused.
+		Func: func(tx *sqlx.Tx) error {
+			for _, genre := range TMDBGenres {
+				if _, err := tx.Exec("INSERT INTO genres (id, name) VALUES (?, ?)", genre.ID, genre.Name); err != nil {
+					return err
+				}
+			}
+
+			var rows []struct {
+				ID     int    `db:"id"`
+				Genres string `db:"genres"`
+			}
+			if err := tx.Select(&rows, "SELECT id, genres FROM movies WHERE genres IS NOT NULL AND genres != ''"); err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				for _, name := range strings.Split(row.Genres, ",") {
+					genre, ok := LookupGenre(name)
+					if !ok {
+						continue
+					}
+					if _, err := tx.Exec("INSERT OR IGNORE INTO movie_genres (movie_id, genre_id) VALUES (?, ?)", row.ID, genre.ID); err != nil {
+						return err
+					}
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		Version: 4,
+		Name:    "credit details",
+		// Credits indexed before are left with NULLs until they're indexed
+		// again
+		SQL: `
+ALTER TABLE credits ADD COLUMN cast_order INTEGER;
+ALTER TABLE credits ADD COLUMN character TEXT;
+ALTER TABLE credits ADD COLUMN credit_id TEXT;
+ALTER TABLE credits ADD COLUMN department TEXT;
+ALTER TABLE credits ADD COLUMN job TEXT;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "movie vote count",
+		SQL: `
+ALTER TABLE movies ADD COLUMN vote_count INTEGER;
+`,
+	},
+	{
+		Version: 6,
+		Name:    "movie metadata",
+		// movies.language already holds the original language
+		SQL: `
+CREATE TABLE collections (
+    id          INTEGER PRIMARY KEY,
+    name        TEXT NOT NULL,
+    poster_path TEXT
+);
+
+ALTER TABLE movies ADD COLUMN original_title TEXT;
+ALTER TABLE movies ADD COLUMN vote_average REAL;
+ALTER TABLE movies ADD COLUMN imdb_id TEXT;
+ALTER TABLE movies ADD COLUMN tagline TEXT;
+ALTER TABLE movies ADD COLUMN overview TEXT;
+ALTER TABLE movies ADD COLUMN budget INTEGER;
+ALTER TABLE movies ADD COLUMN revenue INTEGER;
+ALTER TABLE movies ADD COLUMN collection_id INTEGER REFERENCES collections(id);
+
+CREATE TABLE companies (
+    id              INTEGER PRIMARY KEY,
+    name            TEXT NOT NULL,
+    origin_country  TEXT
+);
+
+CREATE TABLE movie_companies (
+    movie_id    INTEGER NOT NULL,
+    company_id  INTEGER NOT NULL,
+
+    FOREIGN KEY(movie_id) REFERENCES movies(id),
+    FOREIGN KEY(company_id) REFERENCES companies(id),
+    PRIMARY KEY(movie_id, company_id)
+);
+
+CREATE TABLE countries (
+    code    TEXT PRIMARY KEY,
+    name    TEXT NOT NULL
+);
+
+CREATE TABLE movie_countries (
+    movie_id        INTEGER NOT NULL,
+    country_code    TEXT NOT NULL,
+
+    FOREIGN KEY(movie_id) REFERENCES movies(id),
+    FOREIGN KEY(country_code) REFERENCES countries(code),
+    PRIMARY KEY(movie_id, country_code)
+);
+
+CREATE INDEX idx_movies_collection ON movies(collection_id);
+CREATE INDEX idx_movie_companies_company ON movie_companies(company_id);
+`,
+	},
+	{
+		Version: 7,
+		Name:    "search index",
+		// Only created where SQLite has FTS5, see setupSearchIndex
+		Func: setupSearchIndex,
+	},
+}
+
+// Migrations returns every migration, oldest first.
+func Migrations() []Migration {
+	return migrations
+}
+
+// LatestSchemaVersion is the schema version this binary migrates databases to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaTooNewError is returned for a database migrated by a newer version
+// of tmdb-anki.
+type SchemaTooNewError struct {
+	Path    string
+	Version int
+	Latest  int
+}
+
+func (e *SchemaTooNewError) Error() string {
+	return fmt.Sprintf("%s has schema version %d, but this tmdb-anki only knows up to version %d, update tmdb-anki", e.Path, e.Version, e.Latest)
+}
+
+func (d *Database) SchemaVersion() (int, error) {
+	var version int
+	if err := d.conn.Get(&version, "PRAGMA user_version"); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// PendingMigrations returns the migrations that haven't been applied yet, in
+// the order they will be applied.
+func (d *Database) PendingMigrations() ([]Migration, error) {
+	version, err := d.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate applies the pending migrations, each in its own transaction, and
+// returns the applied ones.
+func (d *Database) Migrate() ([]Migration, error) {
+	pending, err := d.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		if err := d.applyMigration(migration); err != nil {
+			return pending[:i], fmt.Errorf("failed to migrate to version %d (%s): %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+func (d *Database) applyMigration(migration Migration) error {
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if migration.SQL != "" {
+		if _, err := tx.Exec(migration.SQL); err != nil {
+			return err
+		}
+	}
+	if migration.Func != nil {
+		if err := migration.Func(tx); err != nil {
+			return err
+		}
+	}
+
+	// PRAGMA doesn't take parameters, the version is an int from the list above
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", migration.Version)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// addMissingColumns adds the columns, given as name and type, that table
+// doesn't have yet.
+func addMissingColumns(tx *sqlx.Tx, table string, columns [][2]string) error {
+	var existing []string
+	if err := tx.Select(&existing, "SELECT name FROM pragma_table_info(?)", table); err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+
+	has := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		has[name] = true
+	}
+
+	for _, column := range columns {
+		if has[column[0]] {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column[0], column[1])); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", table, column[0], err)
+		}
+	}
+
+	return nil
+}
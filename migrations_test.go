@@ -0,0 +1,113 @@
+package tmdbankigenerator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMigrationsNumbering(t *testing.T) {
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("expected migration %q to have version %d, got %d", migration.Name, i+1, migration.Version)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "migrate.db")
+
+	db, err := OpenDatabase(DatabaseOptions{Path: path})
+	if err != nil {
+		t.Fatalf("OpenDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	pending, err := db.PendingMigrations()
+	if err != nil {
+		t.Fatalf("PendingMigrations failed: %v", err)
+	}
+	if len(pending) != len(migrations) {
+		t.Errorf("expected every migration to be pending on a new database, got %d", len(pending))
+	}
+
+	applied, err := db.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("expected %d migrations to be applied, got %d", len(migrations), len(applied))
+	}
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("expected version %d, got %d", LatestSchemaVersion(), version)
+	}
+
+	applied, err = db.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected migrating again to do nothing, applied %d", len(applied))
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	conn, err := sqlx.Connect("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to create DB: %v", err)
+	}
+	conn.MustExec(`
+CREATE TABLE movies (id INTEGER PRIMARY KEY, title TEXT, popularity FLOAT);
+INSERT INTO movies (id, title, popularity) VALUES (550, 'Fight Club', 60);
+`)
+	conn.Close()
+
+	db, err := NewDatabase(DatabaseOptions{Path: path})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	var movie struct {
+		Title  string         `db:"title"`
+		Genres sql.NullString `db:"genres"`
+	}
+	if err := db.conn.Get(&movie, "SELECT title, genres FROM movies WHERE id = 550"); err != nil {
+		t.Fatalf("expected the missing columns to be added: %v", err)
+	}
+	if movie.Title != "Fight Club" {
+		t.Errorf("expected the existing rows to be kept, got %q", movie.Title)
+	}
+}
+
+func TestOpenNewerDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "newer.db")
+
+	conn, err := sqlx.Connect("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to create DB: %v", err)
+	}
+	conn.MustExec(fmt.Sprintf("PRAGMA user_version = %d", LatestSchemaVersion()+1))
+	conn.Close()
+
+	_, err = NewDatabase(DatabaseOptions{Path: path})
+
+	var tooNew *SchemaTooNewError
+	if !errors.As(err, &tooNew) {
+		t.Fatalf("expected a SchemaTooNewError, got %v", err)
+	}
+	if tooNew.Version != LatestSchemaVersion()+1 {
+		t.Errorf("expected version %d, got %d", LatestSchemaVersion()+1, tooNew.Version)
+	}
+}
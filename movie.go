@@ -1,7 +1,10 @@
 package tmdbankigenerator
 
 import (
+	"cmp"
+	"math"
 	"slices"
+	"strings"
 	"time"
 )
 
@@ -16,25 +19,191 @@ const (
 var ValidLanguages = []Language{LanguageDanish, LanguageEnglish}
 
 type Movie struct {
-	ID          int       `db:"id"`
-	Title       string    `db:"title"`
-	Popularity  float32   `db:"popularity"`
-	ReleaseDate time.Time `db:"release_date"`
-	Adult       bool      `db:"adult"`
-	Runtime     int       `db:"runtime"`
-	Language    Language  `db:"language"`
-	Genres      string    `db:"genres"`
+	ID            int       `db:"id"`
+	Title         string    `db:"title"`
+	OriginalTitle string    `db:"original_title"`
+	Popularity    float32   `db:"popularity"`
+	ReleaseDate   time.Time `db:"release_date"`
+	Adult         bool      `db:"adult"`
+	Runtime       int       `db:"runtime"`
+	// The original language
+	Language    Language `db:"language"`
+	VoteCount   int      `db:"vote_count"`
+	VoteAverage float32  `db:"vote_average"`
+	IMDbID      string   `db:"imdb_id"`
+	Tagline     string   `db:"tagline"`
+	Overview    string   `db:"overview"`
+	// In US dollars, 0 when unknown
+	Budget  int64 `db:"budget"`
+	Revenue int64 `db:"revenue"`
+
+	Genres []Genre `db:"-"`
+	// nil when the movie isn't part of a collection
+	Collection *Collection `db:"-"`
+	Companies  []Company   `db:"-"`
+	Countries  []Country   `db:"-"`
 
 	Images  []MovieImage
 	Persons []MoviePerson
 }
 
+// Collection is a series of movies, e.g. "The Lord of the Rings Collection".
+type Collection struct {
+	ID         int    `db:"id"`
+	Name       string `db:"name"`
+	PosterPath string `db:"poster_path"`
+}
+
+// Company is a production company.
+type Company struct {
+	ID            int    `db:"id"`
+	Name          string `db:"name"`
+	OriginCountry string `db:"origin_country"`
+}
+
+// Country is a production country.
+type Country struct {
+	// ISO 3166-1 code, e.g. "US"
+	Code string `db:"code"`
+	Name string `db:"name"`
+}
+
+type Genre struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+// TMDBGenres are the movie genres TMDB knows, see /genre/movie/list
+var TMDBGenres = []Genre{
+	{28, "Action"},
+	{12, "Adventure"},
+	{16, "Animation"},
+	{35, "Comedy"},
+	{80, "Crime"},
+	{99, "Documentary"},
+	{18, "Drama"},
+	{10751, "Family"},
+	{14, "Fantasy"},
+	{36, "History"},
+	{27, "Horror"},
+	{10402, "Music"},
+	{9648, "Mystery"},
+	{10749, "Romance"},
+	{878, "Science Fiction"},
+	{10770, "TV Movie"},
+	{53, "Thriller"},
+	{10752, "War"},
+	{37, "Western"},
+}
+
+// LookupGenre finds a TMDB genre by name, ignoring case.
+func LookupGenre(name string) (Genre, bool) {
+	for _, genre := range TMDBGenres {
+		if strings.EqualFold(genre.Name, strings.TrimSpace(name)) {
+			return genre, true
+		}
+	}
+	return Genre{}, false
+}
+
+// GenreFilter selects movies by genre, by name. A movie matches when it has
+// one of the Include genres, or Include is empty, and none of the Exclude
+// genres.
+type GenreFilter struct {
+	Include []string `toml:"include"`
+	Exclude []string `toml:"exclude"`
+}
+
+func (f GenreFilter) Match(genres []Genre) bool {
+	has := func(names []string) bool {
+		for _, genre := range genres {
+			for _, name := range names {
+				if strings.EqualFold(genre.Name, strings.TrimSpace(name)) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if len(f.Include) > 0 && !has(f.Include) {
+		return false
+	}
+	return !has(f.Exclude)
+}
+
+// GenreNames returns the names of the genres, for showing them on a card.
+func GenreNames(genres []Genre) []string {
+	names := make([]string, len(genres))
+	for i, genre := range genres {
+		names[i] = genre.Name
+	}
+	return names
+}
+
 type MoviePerson struct {
+	// The job shown for the person, Cast if they're in the cast
 	JobType JobType
-	InList  bool
+	// Every job of the person on the movie, JobType first
+	JobTypes []JobType
+	InList   bool
+	// Billing position among the cast, 0 being the lead, or
+	// UnknownCastOrder. Only meaningful for cast.
+	CastOrder int
+	Character string
 	Person
 }
 
+// UnknownCastOrder is the CastOrder of credits indexed before the billing
+// order was stored.
+const UnknownCastOrder = -1
+
+// SortMoviePersons sorts cast by billing order, unknown orders last, and
+// everyone else by name.
+func SortMoviePersons(persons []MoviePerson) {
+	order := func(person MoviePerson) int {
+		if person.JobType != JobTypeCast {
+			return 0
+		}
+		if person.CastOrder == UnknownCastOrder {
+			return math.MaxInt
+		}
+		return person.CastOrder
+	}
+
+	slices.SortStableFunc(persons, func(a, b MoviePerson) int {
+		return cmp.Or(
+			cmp.Compare(order(a), order(b)),
+			strings.Compare(a.Name, b.Name),
+		)
+	})
+}
+
+// TopBilledCast returns the persons sorted with SortMoviePersons, keeping
+// only the n top billed cast members. Cast members from the cast list and
+// people who aren't cast are always kept. n <= 0 keeps everyone.
+func TopBilledCast(persons []MoviePerson, n int) []MoviePerson {
+	sorted := slices.Clone(persons)
+	SortMoviePersons(sorted)
+	if n <= 0 {
+		return sorted
+	}
+
+	result := make([]MoviePerson, 0, len(sorted))
+	billed := 0
+	for _, person := range sorted {
+		if person.JobType == JobTypeCast {
+			billed++
+			if billed > n && !person.InList {
+				continue
+			}
+		}
+		result = append(result, person)
+	}
+
+	return result
+}
+
 type MovieImage struct {
 	MovieID int    `db:"movie_id"`
 	Path    string `db:"path"`
@@ -59,6 +228,15 @@ type Credit struct {
 	PersonID int     `db:"person_id"`
 	MovieID  int     `db:"movie_id"`
 	JobType  JobType `db:"job_type"`
+	// Billing position among the cast, 0 being the lead. Only set on cast
+	// credits.
+	CastOrder int    `db:"cast_order"`
+	Character string `db:"character"`
+	// TMDB's ID of the credit
+	CreditID string `db:"credit_id"`
+	// Department and job as TMDB lists them, only set on crew credits
+	Department string `db:"department"`
+	Job        string `db:"job"`
 
 	Movie *Movie
 }
@@ -91,3 +269,18 @@ func (t JobType) IsValid() bool {
 		return false
 	}
 }
+
+// JobTypes returns every job type credits are stored with.
+func JobTypes() []JobType {
+	return slices.Clone(allJobTypes)
+}
+
+// ParseJobType looks up a job type by name, ignoring case.
+func ParseJobType(name string) (JobType, bool) {
+	for _, jobType := range allJobTypes {
+		if strings.EqualFold(string(jobType), strings.TrimSpace(name)) {
+			return jobType, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,78 @@
+package tmdbankigenerator
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGenreFilter(t *testing.T) {
+	drama, _ := LookupGenre("drama")
+	horror, _ := LookupGenre("Horror")
+	documentary, _ := LookupGenre("Documentary")
+
+	tests := []struct {
+		name   string
+		filter GenreFilter
+		genres []Genre
+		want   bool
+	}{
+		{"Empty filter", GenreFilter{}, []Genre{drama}, true},
+		{"No genres", GenreFilter{}, nil, true},
+		{"Included", GenreFilter{Include: []string{"Drama", "Comedy"}}, []Genre{horror, drama}, true},
+		{"Not included", GenreFilter{Include: []string{"Comedy"}}, []Genre{drama}, false},
+		{"No genres with include", GenreFilter{Include: []string{"Comedy"}}, nil, false},
+		{"Excluded", GenreFilter{Exclude: []string{"horror"}}, []Genre{drama, horror}, false},
+		{"Included and excluded", GenreFilter{Include: []string{"Drama"}, Exclude: []string{"Documentary"}}, []Genre{drama, documentary}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.Match(test.genres); got != test.want {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestTopBilledCast(t *testing.T) {
+	cast := func(name string, order int, inList bool) MoviePerson {
+		return MoviePerson{JobType: JobTypeCast, CastOrder: order, InList: inList, Person: Person{Name: name}}
+	}
+	director := MoviePerson{JobType: JobTypeDirector, Person: Person{Name: "Director"}}
+
+	persons := []MoviePerson{
+		cast("Unknown", UnknownCastOrder, false),
+		cast("Third", 2, true),
+		director,
+		cast("Lead", 0, false),
+		cast("Second", 1, false),
+		cast("Fourth", 3, false),
+	}
+
+	names := func(persons []MoviePerson) []string {
+		names := make([]string, len(persons))
+		for i, person := range persons {
+			names[i] = person.Name
+		}
+		return names
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want []string
+	}{
+		{"Everyone", 0, []string{"Director", "Lead", "Second", "Third", "Fourth", "Unknown"}},
+		{"Top two", 2, []string{"Director", "Lead", "Second", "Third"}},
+		{"Top four", 4, []string{"Director", "Lead", "Second", "Third", "Fourth"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := names(TopBilledCast(persons, test.n))
+			if !slices.Equal(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
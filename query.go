@@ -0,0 +1,391 @@
+package tmdbankigenerator
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// MovieOrder is the order QueryMovies returns movies in.
+type MovieOrder string
+
+const (
+	// Most popular first, the default
+	MovieOrderPopularity MovieOrder = "popularity"
+	// Newest first
+	MovieOrderReleaseDate MovieOrder = "release_date"
+	MovieOrderTitle       MovieOrder = "title"
+)
+
+var movieOrderClauses = map[MovieOrder]string{
+	MovieOrderPopularity:  "m.popularity DESC, m.id",
+	MovieOrderReleaseDate: "m.release_date DESC, m.id",
+	MovieOrderTitle:       "m.title COLLATE NOCASE, m.id",
+}
+
+// MovieQuery selects the movies of a list of people, and who else to list on
+// them. The zero value of each field doesn't filter.
+type MovieQuery struct {
+	// Movies any of these people have a credit on are returned
+	PersonIDs []int
+	// These people are listed on the movies, like PersonIDs, but their
+	// movies aren't returned unless someone in PersonIDs is on them
+	ExtraIDs []int
+	// People not in PersonIDs or ExtraIDs are only listed above this
+	// popularity
+	MinPersonPopularity float32
+
+	// Release years, inclusive
+	FromYear  int
+	ToYear    int
+	Languages []Language
+	// Only credits of these job types select movies and list people
+	JobTypes     []JobType
+	MinVoteCount int
+	IncludeAdult bool
+
+	// At most this many movies are returned
+	Limit   int
+	OrderBy MovieOrder
+}
+
+func (q MovieQuery) validate() error {
+	if q.OrderBy != "" {
+		if _, ok := movieOrderClauses[q.OrderBy]; !ok {
+			return fmt.Errorf("unknown movie order %q", q.OrderBy)
+		}
+	}
+	if q.FromYear != 0 && q.ToYear != 0 && q.FromYear > q.ToYear {
+		return fmt.Errorf("year range %d-%d is empty", q.FromYear, q.ToYear)
+	}
+	for _, jobType := range q.JobTypes {
+		if !jobType.IsValid() {
+			return fmt.Errorf("invalid job type %q", jobType)
+		}
+	}
+	if q.Limit < 0 {
+		return fmt.Errorf("limit must not be negative, got %d", q.Limit)
+	}
+	return nil
+}
+
+// QueryMovies returns the movies selected by query, each with its genres,
+// collection, production companies and countries, images and the people
+// listed on it. A person is listed once with all their job types, cast in
+// billing order.
+func (d *Database) QueryMovies(query MovieQuery) ([]Movie, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	if len(query.PersonIDs) == 0 {
+		return []Movie{}, nil
+	}
+
+	movies, err := d.queryMovieRows(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	persons, err := d.queryMoviePersons(query, ids)
+	if err != nil {
+		return nil, err
+	}
+	images, err := d.movieImages(ids)
+	if err != nil {
+		return nil, err
+	}
+	genres, err := d.movieGenres(ids)
+	if err != nil {
+		return nil, err
+	}
+	companies, err := d.movieCompanies(ids)
+	if err != nil {
+		return nil, err
+	}
+	countries, err := d.movieCountries(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range movies {
+		id := movies[i].ID
+		movies[i].Persons = persons[id]
+		movies[i].Images = images[id]
+		movies[i].Genres = genres[id]
+		movies[i].Companies = companies[id]
+		movies[i].Countries = countries[id]
+	}
+
+	return movies, nil
+}
+
+func (d *Database) queryMovieRows(query MovieQuery) ([]Movie, error) {
+	where := []string{"m.id IN (SELECT c.movie_id FROM credits c WHERE c.person_id IN (?)" + jobTypeCondition(query) + ")"}
+	args := []any{query.PersonIDs}
+	if len(query.JobTypes) > 0 {
+		args = append(args, query.JobTypes)
+	}
+
+	if query.FromYear != 0 {
+		where = append(where, "CAST(substr(m.release_date, 1, 4) AS INTEGER) >= ?")
+		args = append(args, query.FromYear)
+	}
+	if query.ToYear != 0 {
+		where = append(where, "CAST(substr(m.release_date, 1, 4) AS INTEGER) <= ?")
+		args = append(args, query.ToYear)
+	}
+	if len(query.Languages) > 0 {
+		where = append(where, "m.language IN (?)")
+		args = append(args, query.Languages)
+	}
+	if query.MinVoteCount > 0 {
+		where = append(where, "COALESCE(m.vote_count, 0) >= ?")
+		args = append(args, query.MinVoteCount)
+	}
+	if !query.IncludeAdult {
+		where = append(where, "NOT COALESCE(m.adult, 0)")
+	}
+
+	order := movieOrderClauses[MovieOrderPopularity]
+	if query.OrderBy != "" {
+		order = movieOrderClauses[query.OrderBy]
+	}
+
+	sqlQuery := fmt.Sprintf(`
+    SELECT m.id, COALESCE(m.title, '') AS title, COALESCE(m.language, '') AS language,
+           COALESCE(m.popularity, 0) AS popularity, COALESCE(m.runtime, 0) AS runtime,
+           COALESCE(m.release_date, '') AS release_date, COALESCE(m.adult, 0) AS adult,
+           COALESCE(m.vote_count, 0) AS vote_count, COALESCE(m.original_title, '') AS original_title,
+           COALESCE(m.vote_average, 0) AS vote_average, COALESCE(m.imdb_id, '') AS imdb_id,
+           COALESCE(m.tagline, '') AS tagline, COALESCE(m.overview, '') AS overview,
+           COALESCE(m.budget, 0) AS budget, COALESCE(m.revenue, 0) AS revenue,
+           col.id, col.name, col.poster_path
+    FROM movies m
+        LEFT JOIN collections col ON col.id = m.collection_id
+    WHERE %s
+    ORDER BY %s`, strings.Join(where, " AND "), order)
+	if query.Limit > 0 {
+		sqlQuery += "\n    LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	sqlQuery, args, err := sqlx.In(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct query: %w", err)
+	}
+
+	rows, err := d.conn.Queryx(d.conn.Rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies: %w", err)
+	}
+	defer rows.Close()
+
+	movies := []Movie{}
+	for rows.Next() {
+		var movie Movie
+		var releaseDate string
+		var collectionID sql.NullInt64
+		var collectionName, collectionPoster sql.NullString
+		err := rows.Scan(
+			&movie.ID, &movie.Title, &movie.Language, &movie.Popularity, &movie.Runtime, &releaseDate, &movie.Adult,
+			&movie.VoteCount, &movie.OriginalTitle, &movie.VoteAverage, &movie.IMDbID, &movie.Tagline, &movie.Overview,
+			&movie.Budget, &movie.Revenue, &collectionID, &collectionName, &collectionPoster,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %w", err)
+		}
+
+		if collectionID.Valid {
+			movie.Collection = &Collection{
+				ID:         int(collectionID.Int64),
+				Name:       collectionName.String,
+				PosterPath: collectionPoster.String,
+			}
+		}
+
+		movie.ReleaseDate, err = parseReleaseDate(releaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("movie %d: %w", movie.ID, err)
+		}
+
+		movies = append(movies, movie)
+	}
+
+	return movies, rows.Err()
+}
+
+// queryMoviePersons returns the people listed on the movies, by movie ID.
+func (d *Database) queryMoviePersons(query MovieQuery, movieIDs []int) (map[int][]MoviePerson, error) {
+	persons := make(map[int][]MoviePerson)
+	if len(movieIDs) == 0 {
+		return persons, nil
+	}
+
+	listed := slices.Concat(query.PersonIDs, query.ExtraIDs)
+
+	// A NULL cast order is UnknownCastOrder
+	sqlQuery := `
+    SELECT c.movie_id, c.job_type, COALESCE(c.cast_order, -1), COALESCE(c.character, ''),
+           p.id, COALESCE(p.name, ''), COALESCE(p.popularity, 0), COALESCE(p.known_for_department, ''),
+           COALESCE(p.profile_path, ''), c.person_id IN (?) AS in_list
+    FROM credits c
+        INNER JOIN persons p ON p.id = c.person_id
+    WHERE c.movie_id IN (?)` + jobTypeCondition(query)
+	args := []any{listed, movieIDs}
+	if len(query.JobTypes) > 0 {
+		args = append(args, query.JobTypes)
+	}
+	if query.MinPersonPopularity > 0 {
+		sqlQuery += " AND (c.person_id IN (?) OR p.popularity > ?)"
+		args = append(args, listed, query.MinPersonPopularity)
+	}
+	// Ordered so the job types of a person are merged the same every time
+	sqlQuery += " ORDER BY c.movie_id, c.person_id, c.job_type"
+
+	sqlQuery, args, err := sqlx.In(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct query: %w", err)
+	}
+
+	rows, err := d.conn.Queryx(d.conn.Rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query people: %w", err)
+	}
+	defer rows.Close()
+
+	personIDs := []int{}
+	// Index of each person in the persons of a movie
+	indexes := make(map[[2]int]int)
+	for rows.Next() {
+		var movieID int
+		var person MoviePerson
+		err := rows.Scan(
+			&movieID, &person.JobType, &person.CastOrder, &person.Character,
+			&person.ID, &person.Name, &person.Popularity, &person.KnownForDepartment,
+			&person.ProfilePath, &person.InList,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan person: %w", err)
+		}
+
+		key := [2]int{movieID, person.ID}
+		i, ok := indexes[key]
+		if !ok {
+			person.JobTypes = []JobType{person.JobType}
+			indexes[key] = len(persons[movieID])
+			persons[movieID] = append(persons[movieID], person)
+			personIDs = append(personIDs, person.ID)
+			continue
+		}
+
+		listed := &persons[movieID][i]
+		listed.JobTypes = append(listed.JobTypes, person.JobType)
+		if person.JobType == JobTypeCast {
+			listed.CastOrder, listed.Character = person.CastOrder, person.Character
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	slices.Sort(personIDs)
+	images, err := d.personImages(slices.Compact(personIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, moviePersons := range persons {
+		for i := range moviePersons {
+			moviePersons[i].Images = images[moviePersons[i].ID]
+			slices.SortFunc(moviePersons[i].JobTypes, func(a, b JobType) int {
+				return slices.Index(allJobTypes, a) - slices.Index(allJobTypes, b)
+			})
+			moviePersons[i].JobType = moviePersons[i].JobTypes[0]
+		}
+		SortMoviePersons(moviePersons)
+	}
+
+	return persons, nil
+}
+
+// jobTypeCondition restricts the credits c to query.JobTypes, the job types
+// are the next argument.
+func jobTypeCondition(query MovieQuery) string {
+	if len(query.JobTypes) == 0 {
+		return ""
+	}
+	return " AND c.job_type IN (?)"
+}
+
+// movieImages returns the images of the movies, by movie ID.
+func (d *Database) movieImages(movieIDs []int) (map[int][]MovieImage, error) {
+	images := make(map[int][]MovieImage)
+	if len(movieIDs) == 0 {
+		return images, nil
+	}
+
+	query, args, err := sqlx.In("SELECT movie_id, path FROM movie_images WHERE movie_id IN (?) AND path != '' ORDER BY movie_id, path", movieIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct query: %w", err)
+	}
+
+	var rows []MovieImage
+	if err := d.conn.Select(&rows, d.conn.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to query movie images: %w", err)
+	}
+	for _, image := range rows {
+		images[image.MovieID] = append(images[image.MovieID], image)
+	}
+
+	return images, nil
+}
+
+// personImages returns the images of the people, by person ID.
+func (d *Database) personImages(personIDs []int) (map[int][]PersonImage, error) {
+	images := make(map[int][]PersonImage)
+	if len(personIDs) == 0 {
+		return images, nil
+	}
+
+	query, args, err := sqlx.In("SELECT person_id, path FROM person_images WHERE person_id IN (?) AND path != '' ORDER BY person_id, path", personIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct query: %w", err)
+	}
+
+	var rows []PersonImage
+	if err := d.conn.Select(&rows, d.conn.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to query person images: %w", err)
+	}
+	for _, image := range rows {
+		images[image.PersonID] = append(images[image.PersonID], image)
+	}
+
+	return images, nil
+}
+
+// parseReleaseDate parses a release date the way the SQLite driver writes
+// time.Time, or as TMDB's YYYY-MM-DD. An empty date is the zero time.
+func parseReleaseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	value = strings.TrimSuffix(value, "Z")
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid release date %q", value)
+}
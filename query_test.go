@@ -0,0 +1,218 @@
+package tmdbankigenerator
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestQueryDatabase(t *testing.T) *Database {
+	t.Helper()
+
+	db, err := NewDatabase(DatabaseOptions{InMemory: true})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	date := func(year int) time.Time { return time.Date(year, 6, 1, 0, 0, 0, 0, time.UTC) }
+	movies := []Movie{
+		{ID: 1, Title: "Old English", Language: LanguageEnglish, Popularity: 10, VoteCount: 500, ReleaseDate: date(1980),
+			Images: []MovieImage{{MovieID: 1, Path: "/old.jpg"}, {MovieID: 1, Path: "/old2.jpg"}}},
+		{ID: 2, Title: "New Danish", Language: LanguageDanish, Popularity: 30, VoteCount: 50, ReleaseDate: date(2015)},
+		{ID: 3, Title: "Adult", Language: LanguageEnglish, Popularity: 20, VoteCount: 500, ReleaseDate: date(2000), Adult: true},
+		{ID: 4, Title: "Without Lead", Language: LanguageEnglish, Popularity: 40, VoteCount: 500, ReleaseDate: date(2000)},
+	}
+	people := []Person{
+		{ID: 10, Name: "Lead", Popularity: 5, Images: []PersonImage{{PersonID: 10, Path: "/a.jpg"}, {PersonID: 10, Path: "/b.jpg"}}},
+		{ID: 11, Name: "Extra", Popularity: 1},
+		{ID: 12, Name: "Star", Popularity: 50},
+		{ID: 13, Name: "Nobody", Popularity: 2},
+	}
+	credits := []Credit{
+		{PersonID: 10, MovieID: 1, JobType: JobTypeCast, CastOrder: 1, Character: "Hero"},
+		{PersonID: 10, MovieID: 1, JobType: JobTypeDirector},
+		{PersonID: 10, MovieID: 2, JobType: JobTypeDirector},
+		{PersonID: 10, MovieID: 3, JobType: JobTypeCast},
+		{PersonID: 11, MovieID: 1, JobType: JobTypeCast, CastOrder: 2},
+		{PersonID: 11, MovieID: 4, JobType: JobTypeCast},
+		{PersonID: 12, MovieID: 1, JobType: JobTypeCast, CastOrder: 0},
+		{PersonID: 13, MovieID: 1, JobType: JobTypeCast, CastOrder: 3},
+	}
+
+	if err := db.UpsertMovies(movies); err != nil {
+		t.Fatalf("UpsertMovies failed: %v", err)
+	}
+	if err := db.UpsertPeople(people); err != nil {
+		t.Fatalf("UpsertPeople failed: %v", err)
+	}
+	if err := db.UpsertCredits(credits); err != nil {
+		t.Fatalf("UpsertCredits failed: %v", err)
+	}
+
+	return db
+}
+
+func TestQueryMovies(t *testing.T) {
+	db := newTestQueryDatabase(t)
+
+	ids := func(movies []Movie) []int {
+		ids := make([]int, len(movies))
+		for i, movie := range movies {
+			ids[i] = movie.ID
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name  string
+		query MovieQuery
+		want  []int
+	}{
+		{"No people", MovieQuery{}, []int{}},
+		{"By popularity", MovieQuery{PersonIDs: []int{10}}, []int{2, 1}},
+		{"Extras don't select movies", MovieQuery{PersonIDs: []int{10}, ExtraIDs: []int{11}}, []int{2, 1}},
+		{"Adult", MovieQuery{PersonIDs: []int{10}, IncludeAdult: true}, []int{2, 3, 1}},
+		{"Year range", MovieQuery{PersonIDs: []int{10}, FromYear: 1970, ToYear: 1990}, []int{1}},
+		{"Languages", MovieQuery{PersonIDs: []int{10}, Languages: []Language{LanguageDanish}}, []int{2}},
+		{"Job types", MovieQuery{PersonIDs: []int{10}, JobTypes: []JobType{JobTypeCast}}, []int{1}},
+		{"Vote count", MovieQuery{PersonIDs: []int{10}, MinVoteCount: 100}, []int{1}},
+		{"Title order", MovieQuery{PersonIDs: []int{10}, OrderBy: MovieOrderTitle}, []int{2, 1}},
+		{"Release date order", MovieQuery{PersonIDs: []int{10, 11}, OrderBy: MovieOrderReleaseDate}, []int{2, 4, 1}},
+		{"Limit", MovieQuery{PersonIDs: []int{10, 11}, Limit: 2}, []int{4, 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			movies, err := db.QueryMovies(test.query)
+			if err != nil {
+				t.Fatalf("QueryMovies failed: %v", err)
+			}
+			if got := ids(movies); !slices.Equal(got, test.want) {
+				t.Errorf("expected movies %v, got %v", test.want, got)
+			}
+		})
+	}
+
+	t.Run("Persons", func(t *testing.T) {
+		movies, err := db.QueryMovies(MovieQuery{PersonIDs: []int{10}, ExtraIDs: []int{11}, MinPersonPopularity: 10, Languages: []Language{LanguageEnglish}})
+		if err != nil {
+			t.Fatalf("QueryMovies failed: %v", err)
+		}
+		if len(movies) != 1 {
+			t.Fatalf("expected 1 movie, got %d", len(movies))
+		}
+
+		movie := movies[0]
+		if len(movie.Images) != 2 || movie.ReleaseDate.Year() != 1980 {
+			t.Errorf("unexpected movie %+v", movie)
+		}
+
+		var got []string
+		for _, person := range movie.Persons {
+			got = append(got, string(person.JobType)+" "+person.Name)
+			if (person.ID == 10 || person.ID == 11) != person.InList {
+				t.Errorf("unexpected InList %v for %s", person.InList, person.Name)
+			}
+		}
+		// Nobody is below the popularity and not in a list, the lead is
+		// listed once as cast despite also directing and having two images
+		want := []string{"Cast Star", "Cast Lead", "Cast Extra"}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		lead := movie.Persons[1]
+		if len(lead.Images) != 2 || lead.Character != "Hero" || lead.CastOrder != 1 {
+			t.Errorf("unexpected lead %+v", lead)
+		}
+		if !slices.Equal(lead.JobTypes, []JobType{JobTypeCast, JobTypeDirector}) {
+			t.Errorf("expected the lead's job types to be merged, got %v", lead.JobTypes)
+		}
+	})
+
+	t.Run("No popularity filter", func(t *testing.T) {
+		if err := db.UpsertPeople([]Person{{ID: 14, Name: "Unrated", Popularity: 0}}); err != nil {
+			t.Fatalf("UpsertPeople failed: %v", err)
+		}
+		if err := db.UpsertCredits([]Credit{{PersonID: 14, MovieID: 1, JobType: JobTypeCast, CastOrder: 4}}); err != nil {
+			t.Fatalf("UpsertCredits failed: %v", err)
+		}
+
+		movies, err := db.QueryMovies(MovieQuery{PersonIDs: []int{12}, ToYear: 1990})
+		if err != nil {
+			t.Fatalf("QueryMovies failed: %v", err)
+		}
+		if len(movies) != 1 {
+			t.Fatalf("expected 1 movie, got %d", len(movies))
+		}
+
+		var got []string
+		for _, person := range movies[0].Persons {
+			got = append(got, person.Name)
+		}
+		// Unrated has no popularity at all, but the zero value doesn't filter
+		want := []string{"Star", "Lead", "Extra", "Nobody", "Unrated"}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		movie := Movie{
+			ID: 5, Title: "Sequel", OriginalTitle: "Sequel II", Language: LanguageEnglish, ReleaseDate: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+			Tagline: "Again.", Budget: 1000, Revenue: 2000, VoteAverage: 6.5,
+			Collection: &Collection{ID: 7, Name: "Sequels"},
+			Companies:  []Company{{ID: 3, Name: "Studio", OriginCountry: "US"}},
+			Countries:  []Country{{Code: "US", Name: "United States of America"}},
+		}
+		if err := db.UpsertMovies([]Movie{movie}); err != nil {
+			t.Fatalf("UpsertMovies failed: %v", err)
+		}
+		if err := db.UpsertCredits([]Credit{{PersonID: 12, MovieID: 5, JobType: JobTypeCast}}); err != nil {
+			t.Fatalf("UpsertCredits failed: %v", err)
+		}
+
+		query := func() Movie {
+			movies, err := db.QueryMovies(MovieQuery{PersonIDs: []int{12}, FromYear: 2001, ToYear: 2001})
+			if err != nil {
+				t.Fatalf("QueryMovies failed: %v", err)
+			}
+			if len(movies) != 1 {
+				t.Fatalf("expected 1 movie, got %d", len(movies))
+			}
+			return movies[0]
+		}
+
+		got := query()
+		if got.OriginalTitle != "Sequel II" || got.Tagline != "Again." || got.Budget != 1000 || got.Revenue != 2000 || got.VoteAverage != 6.5 {
+			t.Errorf("unexpected metadata %+v", got)
+		}
+		if got.Collection == nil || got.Collection.Name != "Sequels" {
+			t.Errorf("expected the Sequels collection, got %+v", got.Collection)
+		}
+		if len(got.Companies) != 1 || got.Companies[0].Name != "Studio" || len(got.Countries) != 1 || got.Countries[0].Code != "US" {
+			t.Errorf("unexpected companies %v and countries %v", got.Companies, got.Countries)
+		}
+
+		movie.Collection = nil
+		movie.Companies = nil
+		if err := db.UpsertMovies([]Movie{movie}); err != nil {
+			t.Fatalf("UpsertMovies failed: %v", err)
+		}
+		if got := query(); got.Collection != nil || len(got.Companies) != 0 {
+			t.Errorf("expected the collection and companies to be removed, got %+v and %v", got.Collection, got.Companies)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, query := range []MovieQuery{
+			{PersonIDs: []int{10}, OrderBy: "rating"},
+			{PersonIDs: []int{10}, FromYear: 2000, ToYear: 1990},
+			{PersonIDs: []int{10}, JobTypes: []JobType{"Gaffer"}},
+		} {
+			if _, err := db.QueryMovies(query); err == nil {
+				t.Errorf("expected %+v to be invalid", query)
+			}
+		}
+	})
+}
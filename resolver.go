@@ -0,0 +1,320 @@
+package tmdbankigenerator
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// A name is only resolved to the most popular of several people sharing it
+// when that person is this many times more popular than the runner-up.
+const dominanceRatio = 5
+
+// maxSuggestions is how many candidates are shown for an unknown or
+// ambiguous name.
+const maxSuggestions = 5
+
+type PersonCandidate struct {
+	ID                 int
+	Name               string
+	AlsoKnownAs        []string
+	Popularity         float32
+	KnownForDepartment string
+}
+
+type Resolution struct {
+	Name   string
+	Person PersonCandidate
+	// How the name was matched: "pinned", "name", "also known as" or "fuzzy"
+	Match string
+}
+
+type NotFoundError struct {
+	Name        string
+	Suggestions []PersonCandidate
+}
+
+func (e *NotFoundError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("could not find %q", e.Name)
+	}
+	return fmt.Sprintf("could not find %q, did you mean: %s", e.Name, formatCandidates(e.Suggestions))
+}
+
+type AmbiguousNameError struct {
+	Name       string
+	Candidates []PersonCandidate
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("%q matches %d people, pin one of them in [cast.pins]: %s", e.Name, len(e.Candidates), formatCandidates(e.Candidates))
+}
+
+type ResolveErrors []error
+
+func (e ResolveErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func formatCandidates(candidates []PersonCandidate) string {
+	parts := make([]string, len(candidates))
+	for i, c := range candidates {
+		department := c.KnownForDepartment
+		if department == "" {
+			department = "unknown department"
+		}
+		parts[i] = fmt.Sprintf("%s (id %d, %s, popularity %.1f)", c.Name, c.ID, department, c.Popularity)
+	}
+	return strings.Join(parts, "; ")
+}
+
+type PersonResolver struct {
+	people  []PersonCandidate
+	byID    map[int]int
+	byName  map[string][]int
+	byAlias map[string][]int
+}
+
+func NewPersonResolver(people []PersonCandidate) *PersonResolver {
+	r := &PersonResolver{
+		people:  people,
+		byID:    make(map[int]int, len(people)),
+		byName:  make(map[string][]int, len(people)),
+		byAlias: make(map[string][]int),
+	}
+
+	for i, person := range people {
+		r.byID[person.ID] = i
+
+		key := NormalizeName(person.Name)
+		r.byName[key] = append(r.byName[key], i)
+
+		for _, alias := range person.AlsoKnownAs {
+			key := NormalizeName(alias)
+			r.byAlias[key] = append(r.byAlias[key], i)
+		}
+	}
+
+	return r
+}
+
+// Resolve finds the person meant by name. Exact matches on the name win over
+// also-known-as matches, which win over names within a small edit distance.
+func (r *PersonResolver) Resolve(name string) (Resolution, error) {
+	key := NormalizeName(name)
+
+	for _, lookup := range []struct {
+		index map[string][]int
+		match string
+	}{
+		{r.byName, "name"},
+		{r.byAlias, "also known as"},
+	} {
+		if indexes, ok := lookup.index[key]; ok {
+			person, err := r.pick(name, indexes)
+			if err != nil {
+				return Resolution{}, err
+			}
+			return Resolution{Name: name, Person: person, Match: lookup.match}, nil
+		}
+	}
+
+	closest := r.closest(key)
+	if len(closest) == 0 {
+		return Resolution{}, &NotFoundError{Name: name}
+	}
+
+	person, err := r.pick(name, closest)
+	if err != nil {
+		return Resolution{}, &NotFoundError{Name: name, Suggestions: err.(*AmbiguousNameError).Candidates}
+	}
+	return Resolution{Name: name, Person: person, Match: "fuzzy"}, nil
+}
+
+// ResolveAll resolves every name, using the pinned ID where there is one. All
+// failures are returned together.
+func (r *PersonResolver) ResolveAll(names []string, pins map[string]int) ([]Resolution, error) {
+	var errs ResolveErrors
+	resolutions := make([]Resolution, 0, len(names))
+
+	for _, name := range names {
+		if id, ok := pins[name]; ok {
+			person := PersonCandidate{ID: id, Name: name}
+			if i, ok := r.byID[id]; ok {
+				person = r.people[i]
+			}
+			resolutions = append(resolutions, Resolution{Name: name, Person: person, Match: "pinned"})
+			continue
+		}
+
+		resolution, err := r.Resolve(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resolutions = append(resolutions, resolution)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return resolutions, nil
+}
+
+// pick chooses between people matching the same name, preferring a clearly
+// more popular person and giving up otherwise.
+func (r *PersonResolver) pick(name string, indexes []int) (PersonCandidate, error) {
+	candidates := make([]PersonCandidate, 0, len(indexes))
+	for _, i := range indexes {
+		if !slices.ContainsFunc(candidates, func(c PersonCandidate) bool { return c.ID == r.people[i].ID }) {
+			candidates = append(candidates, r.people[i])
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b PersonCandidate) int {
+		return cmp.Compare(b.Popularity, a.Popularity)
+	})
+
+	if len(candidates) == 1 || candidates[0].Popularity >= candidates[1].Popularity*dominanceRatio {
+		return candidates[0], nil
+	}
+
+	return PersonCandidate{}, &AmbiguousNameError{
+		Name:       name,
+		Candidates: candidates[:min(len(candidates), maxSuggestions)],
+	}
+}
+
+// closest returns the people whose name or also-known-as is nearest to key,
+// within a distance that scales with the length of the name.
+func (r *PersonResolver) closest(key string) []int {
+	target := []rune(key)
+	maxDistance := 1
+	if len(target) >= 10 {
+		maxDistance = 2
+	}
+
+	best := maxDistance
+	var indexes []int
+
+	consider := func(candidate string, i int) {
+		runes := []rune(candidate)
+		if abs(len(runes)-len(target)) > best {
+			return
+		}
+
+		distance := editDistance(target, runes, best+1)
+		switch {
+		case distance > best:
+			return
+		case distance < best:
+			best = distance
+			indexes = []int{i}
+		case distance == best:
+			indexes = append(indexes, i)
+		}
+	}
+
+	for name, people := range r.byName {
+		for _, i := range people {
+			consider(name, i)
+		}
+	}
+	for alias, people := range r.byAlias {
+		for _, i := range people {
+			consider(alias, i)
+		}
+	}
+
+	return indexes
+}
+
+// NormalizeName folds a name for comparison: case, accents and punctuation
+// are dropped, so "Pilou Asbæk", "pilou asbaek" and "Pilou-Asbaek" are equal.
+func NormalizeName(name string) string {
+	var sb strings.Builder
+	space := false
+
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r), r == '.', r == '\'', r == '’':
+			continue
+		case unicode.IsSpace(r), r == '-', r == '_':
+			space = sb.Len() > 0
+			continue
+		}
+
+		if space {
+			sb.WriteRune(' ')
+			space = false
+		}
+
+		if folded, ok := foldedLetters[r]; ok {
+			sb.WriteString(folded)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// Letters that don't decompose into a base letter and an accent
+var foldedLetters = map[rune]string{
+	'æ': "ae",
+	'ø': "o",
+	'œ': "oe",
+	'ß': "ss",
+	'đ': "d",
+	'ð': "d",
+	'ł': "l",
+	'þ': "th",
+	'ı': "i",
+}
+
+// editDistance is the Levenshtein distance between a and b. It stops early
+// and returns limit once the distance is known to be at least limit.
+func editDistance(a, b []rune, limit int) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+
+		if rowMin >= limit {
+			return limit
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
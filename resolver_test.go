@@ -0,0 +1,97 @@
+package tmdbankigenerator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Pilou Asbæk", "pilou asbaek"},
+		{"Stellan Skarsgård", "stellan skarsgard"},
+		{"Zoe Saldaña", "zoe saldana"},
+		{"Jean-Claude Van Damme", "jean claude van damme"},
+		{"Samuel L. Jackson", "samuel l jackson"},
+		{"  Timothée   Chalamet ", "timothee chalamet"},
+	}
+
+	for _, test := range tests {
+		if result := NormalizeName(test.name); result != test.expected {
+			t.Errorf("NormalizeName(%q) = %q; want %q", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestPersonResolver(t *testing.T) {
+	resolver := NewPersonResolver([]PersonCandidate{
+		{ID: 1, Name: "Tom Hardy", Popularity: 40, KnownForDepartment: "Acting"},
+		{ID: 2, Name: "Tom Hardy", Popularity: 0.6, KnownForDepartment: "Sound"},
+		{ID: 3, Name: "Chris Evans", Popularity: 30, KnownForDepartment: "Acting"},
+		{ID: 4, Name: "Chris Evans", Popularity: 12, KnownForDepartment: "Writing"},
+		{ID: 5, Name: "Lee Byung-hun", AlsoKnownAs: []string{"이병헌"}, Popularity: 20},
+		{ID: 6, Name: "Pilou Asbæk", Popularity: 15},
+		{ID: 7, Name: "Scarlett Johansson", Popularity: 50},
+	})
+
+	tests := []struct {
+		name  string
+		id    int
+		match string
+	}{
+		{"tom hardy", 1, "name"},
+		{"Pilou Asbaek", 6, "name"},
+		{"이병헌", 5, "also known as"},
+		{"Scarlet Johanson", 7, "fuzzy"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolution, err := resolver.Resolve(test.name)
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if resolution.Person.ID != test.id || resolution.Match != test.match {
+				t.Errorf("expected id %d by %s, got id %d by %s", test.id, test.match, resolution.Person.ID, resolution.Match)
+			}
+		})
+	}
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		_, err := resolver.Resolve("Chris Evans")
+
+		var ambiguous *AmbiguousNameError
+		if !errors.As(err, &ambiguous) {
+			t.Fatalf("expected AmbiguousNameError, got %v", err)
+		}
+		if len(ambiguous.Candidates) != 2 || ambiguous.Candidates[0].ID != 3 {
+			t.Errorf("expected both candidates, most popular first, got %+v", ambiguous.Candidates)
+		}
+	})
+
+	t.Run("Pinned", func(t *testing.T) {
+		resolutions, err := resolver.ResolveAll([]string{"Chris Evans", "Tom Hardy"}, map[string]int{"Chris Evans": 4})
+		if err != nil {
+			t.Fatalf("ResolveAll failed: %v", err)
+		}
+		if resolutions[0].Person.ID != 4 || resolutions[0].Match != "pinned" {
+			t.Errorf("expected pinned id 4, got %+v", resolutions[0])
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := resolver.ResolveAll([]string{"Nobody At All", "Tom Hardy"}, nil)
+
+		var errs ResolveErrors
+		if !errors.As(err, &errs) || len(errs) != 1 {
+			t.Fatalf("expected one error, got %v", err)
+		}
+
+		var notFound *NotFoundError
+		if !errors.As(errs[0], &notFound) {
+			t.Errorf("expected NotFoundError, got %v", errs[0])
+		}
+	})
+}
@@ -0,0 +1,315 @@
+package tmdbankigenerator
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrSearchUnavailable is returned by searches when SQLite was built without
+// FTS5, which the search index needs.
+var ErrSearchUnavailable = errors.New("search needs tmdb-anki built with -tags sqlite_fts5")
+
+// searchIndexSQL creates the search index: external content tables, kept in
+// sync with every upsert by the triggers.
+const searchIndexSQL = `
+
+CREATE VIRTUAL TABLE IF NOT EXISTS person_search USING fts5(
+    name, also_known_as,
+    content='persons', content_rowid='id',
+    tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS persons_search_after_insert AFTER INSERT ON persons BEGIN
+    INSERT INTO person_search (rowid, name, also_known_as) VALUES (new.id, new.name, new.also_known_as);
+END;
+CREATE TRIGGER IF NOT EXISTS persons_search_after_delete AFTER DELETE ON persons BEGIN
+    INSERT INTO person_search (person_search, rowid, name, also_known_as) VALUES ('delete', old.id, old.name, old.also_known_as);
+END;
+CREATE TRIGGER IF NOT EXISTS persons_search_after_update AFTER UPDATE ON persons BEGIN
+    INSERT INTO person_search (person_search, rowid, name, also_known_as) VALUES ('delete', old.id, old.name, old.also_known_as);
+    INSERT INTO person_search (rowid, name, also_known_as) VALUES (new.id, new.name, new.also_known_as);
+END;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS movie_search USING fts5(
+    title, original_title,
+    content='movies', content_rowid='id',
+    tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS movies_search_after_insert AFTER INSERT ON movies BEGIN
+    INSERT INTO movie_search (rowid, title, original_title) VALUES (new.id, new.title, new.original_title);
+END;
+CREATE TRIGGER IF NOT EXISTS movies_search_after_delete AFTER DELETE ON movies BEGIN
+    INSERT INTO movie_search (movie_search, rowid, title, original_title) VALUES ('delete', old.id, old.title, old.original_title);
+END;
+CREATE TRIGGER IF NOT EXISTS movies_search_after_update AFTER UPDATE ON movies BEGIN
+    INSERT INTO movie_search (movie_search, rowid, title, original_title) VALUES ('delete', old.id, old.title, old.original_title);
+    INSERT INTO movie_search (rowid, title, original_title) VALUES (new.id, new.title, new.original_title);
+END;
+`
+
+var searchTriggers = []string{
+	"persons_search_after_insert", "persons_search_after_delete", "persons_search_after_update",
+	"movies_search_after_insert", "movies_search_after_delete", "movies_search_after_update",
+}
+
+// hasFTS5 reports whether SQLite was built with FTS5, which go-sqlite3 only
+// does with the sqlite_fts5 tag.
+func hasFTS5(q sqlx.Queryer) (bool, error) {
+	var enabled bool
+	if err := sqlx.Get(q, &enabled, "SELECT sqlite_compileoption_used('ENABLE_FTS5')"); err != nil {
+		return false, fmt.Errorf("failed to check for FTS5: %w", err)
+	}
+	return enabled, nil
+}
+
+// setupSearchIndex creates the search index where SQLite has FTS5, and
+// rebuilds it when its triggers were missing. Without FTS5 the triggers are
+// dropped instead, as they'd fail every write to persons and movies; the
+// index is rebuilt once a binary with FTS5 opens the database again.
+func setupSearchIndex(tx *sqlx.Tx) error {
+	fts5, err := hasFTS5(tx)
+	if err != nil {
+		return err
+	}
+
+	if !fts5 {
+		for _, trigger := range searchTriggers {
+			if _, err := tx.Exec("DROP TRIGGER IF EXISTS " + trigger); err != nil {
+				return fmt.Errorf("failed to drop search trigger %s: %w", trigger, err)
+			}
+		}
+		return nil
+	}
+
+	var triggers int
+	query, args, err := sqlx.In("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?)", searchTriggers)
+	if err != nil {
+		return fmt.Errorf("failed to construct query: %w", err)
+	}
+	if err := tx.Get(&triggers, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("failed to count search triggers: %w", err)
+	}
+	if triggers == len(searchTriggers) {
+		return nil
+	}
+
+	if _, err := tx.Exec(searchIndexSQL); err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+	for _, table := range []string{"person_search", "movie_search"} {
+		if _, err := tx.Exec(fmt.Sprintf("INSERT INTO %[1]s (%[1]s) VALUES ('rebuild')", table)); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// updateSearchIndex runs setupSearchIndex on a migrated database.
+func (d *Database) updateSearchIndex() error {
+	tx, err := d.conn.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setupSearchIndex(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkSearch returns ErrSearchUnavailable when the database can't be
+// searched.
+func (d *Database) checkSearch() error {
+	fts5, err := hasFTS5(d.conn)
+	if err != nil {
+		return err
+	}
+	if !fts5 {
+		return ErrSearchUnavailable
+	}
+
+	var tables int
+	if err := d.conn.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE name IN ('person_search', 'movie_search')"); err != nil {
+		return fmt.Errorf("failed to look up the search index: %w", err)
+	}
+	if tables != 2 {
+		return errors.New("the database has no search index yet, run a command that writes to it, e.g. migrate")
+	}
+	return nil
+}
+
+// SearchMatch is how well a search result matched, best first.
+type SearchMatch int
+
+const (
+	// The name or title equals the query, ignoring case, accents and
+	// punctuation
+	SearchMatchExact SearchMatch = iota
+	// Every word of the query starts a word of the name or title
+	SearchMatchName
+	// Only an also-known-as name or the original title matched
+	SearchMatchAlias
+)
+
+func (m SearchMatch) String() string {
+	switch m {
+	case SearchMatchExact:
+		return "exact"
+	case SearchMatchName:
+		return "name"
+	case SearchMatchAlias:
+		return "alias"
+	}
+	return fmt.Sprintf("SearchMatch(%d)", int(m))
+}
+
+type PersonSearchResult struct {
+	Person
+	Match SearchMatch
+}
+
+type MovieSearchResult struct {
+	Movie
+	Match SearchMatch
+}
+
+// SearchPeople finds people whose name or also-known-as names have words
+// starting with every word of query, ignoring case and accents. Results are
+// ranked by how well they match, then by their bm25 rank, where names weigh
+// more than aliases, then by popularity. limit <= 0 returns every match.
+func (d *Database) SearchPeople(query string, limit int) ([]PersonSearchResult, error) {
+	if err := d.checkSearch(); err != nil {
+		return nil, err
+	}
+	match, ok := searchExpression(query)
+	if !ok {
+		return []PersonSearchResult{}, nil
+	}
+
+	people, err := d.queryPeople(`
+    SELECT p.id, p.name, p.popularity, p.known_for_department, p.also_known_as
+    FROM person_search s
+        INNER JOIN persons p ON p.id = s.rowid
+    WHERE person_search MATCH ?
+    ORDER BY bm25(person_search, 10.0, 1.0), p.popularity DESC, p.id
+    `, match)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search people: %w", err)
+	}
+
+	results := make([]PersonSearchResult, len(people))
+	for i, person := range people {
+		results[i] = PersonSearchResult{Person: person, Match: searchMatch(query, person.Name)}
+	}
+
+	// Stable, so each match keeps the order of the query
+	slices.SortStableFunc(results, func(a, b PersonSearchResult) int {
+		return cmp.Compare(a.Match, b.Match)
+	})
+
+	return truncate(results, limit), nil
+}
+
+// SearchMovies finds movies whose title or original title have words starting
+// with every word of query, ranked like SearchPeople.
+func (d *Database) SearchMovies(query string, limit int) ([]MovieSearchResult, error) {
+	if err := d.checkSearch(); err != nil {
+		return nil, err
+	}
+	match, ok := searchExpression(query)
+	if !ok {
+		return []MovieSearchResult{}, nil
+	}
+
+	rows, err := d.conn.Queryx(`
+    SELECT m.id, COALESCE(m.title, ''), COALESCE(m.original_title, ''), COALESCE(m.language, ''),
+           COALESCE(m.popularity, 0), COALESCE(m.release_date, '')
+    FROM movie_search s
+        INNER JOIN movies m ON m.id = s.rowid
+    WHERE movie_search MATCH ?
+    ORDER BY bm25(movie_search, 10.0, 1.0), m.popularity DESC, m.id
+    `, match)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search movies: %w", err)
+	}
+	defer rows.Close()
+
+	results := []MovieSearchResult{}
+	for rows.Next() {
+		var movie Movie
+		var releaseDate string
+		if err := rows.Scan(&movie.ID, &movie.Title, &movie.OriginalTitle, &movie.Language, &movie.Popularity, &releaseDate); err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %w", err)
+		}
+		if movie.ReleaseDate, err = parseReleaseDate(releaseDate); err != nil {
+			return nil, fmt.Errorf("movie %d: %w", movie.ID, err)
+		}
+
+		results = append(results, MovieSearchResult{Movie: movie, Match: searchMatch(query, movie.Title)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	slices.SortStableFunc(results, func(a, b MovieSearchResult) int {
+		return cmp.Compare(a.Match, b.Match)
+	})
+
+	return truncate(results, limit), nil
+}
+
+// searchExpression turns a query into a full-text search expression matching
+// the words of the query as prefixes. It's false when the query has no words.
+func searchExpression(query string) (string, bool) {
+	words := searchWords(query)
+	if len(words) == 0 {
+		return "", false
+	}
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"*`
+	}
+	return strings.Join(terms, " "), true
+}
+
+// searchWords splits text into words the way the search index tokenizes it,
+// which also drops the operators of the search syntax.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// searchMatch ranks a result by its name or title, anything else matched an
+// alias.
+func searchMatch(query, name string) SearchMatch {
+	if NormalizeName(query) == NormalizeName(name) {
+		return SearchMatchExact
+	}
+
+	nameWords := searchWords(NormalizeName(name))
+	for _, word := range searchWords(NormalizeName(query)) {
+		if !slices.ContainsFunc(nameWords, func(nameWord string) bool {
+			return strings.HasPrefix(nameWord, word)
+		}) {
+			return SearchMatchAlias
+		}
+	}
+	return SearchMatchName
+}
+
+func truncate[T any](s []T, limit int) []T {
+	if limit > 0 && len(s) > limit {
+		return s[:limit]
+	}
+	return s
+}
@@ -0,0 +1,130 @@
+package tmdbankigenerator
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// skipWithoutSearch skips the rest of a test when SQLite was built without
+// FTS5.
+func skipWithoutSearch(t *testing.T, db *Database) {
+	t.Helper()
+	if err := db.checkSearch(); errors.Is(err, ErrSearchUnavailable) {
+		t.Skip("SQLite was built without FTS5, build with -tags sqlite_fts5")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db, err := NewDatabase(DatabaseOptions{InMemory: true})
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer db.Close()
+	skipWithoutSearch(t, db)
+
+	for _, person := range []Person{
+		{ID: 1, Name: "Tom Hanks", Popularity: 40, AlsoKnownAs: []string{"Thomas Jeffrey Hanks"}},
+		{ID: 2, Name: "Tom Hardy", Popularity: 50},
+		{ID: 3, Name: "Pilou Asbæk", Popularity: 10, AlsoKnownAs: []string{"Johan Philip Asbæk"}},
+		{ID: 4, Name: "Zoë Kravitz", Popularity: 30},
+		{ID: 5, Name: "Tom Holland Stanley Smith Junior Esquire", Popularity: 60},
+	} {
+		if err := db.UpsertPerson(person, nil); err != nil {
+			t.Fatalf("UpsertPerson failed: %v", err)
+		}
+	}
+	if err := db.UpsertMovies([]Movie{
+		{ID: 10, Title: "The Hunt", OriginalTitle: "Jagten", Popularity: 20},
+		{ID: 11, Title: "Hunt", Popularity: 5},
+		{ID: 12, Title: "The Hunted", Popularity: 30},
+	}); err != nil {
+		t.Fatalf("UpsertMovies failed: %v", err)
+	}
+
+	people := func(query string) []int {
+		results, err := db.SearchPeople(query, 0)
+		if err != nil {
+			t.Fatalf("SearchPeople(%q) failed: %v", query, err)
+		}
+		ids := make([]int, len(results))
+		for i, result := range results {
+			ids[i] = result.ID
+		}
+		return ids
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// bm25 ranks the longer name last despite its popularity
+		{"tom", []int{2, 1, 5}},
+		{"Tom Hanks", []int{1}},
+		{"thomas", []int{1}},
+		{"zoe", []int{4}},
+		{"asbæk", []int{3}},
+		{"philip", []int{3}},
+		{"\"OR*", []int{}},
+		{"", []int{}},
+	}
+	for _, test := range tests {
+		if got := people(test.query); !slices.Equal(got, test.want) {
+			t.Errorf("SearchPeople(%q): expected %v, got %v", test.query, test.want, got)
+		}
+	}
+
+	t.Run("Kept in sync", func(t *testing.T) {
+		if err := db.UpsertPeople([]Person{{ID: 2, Name: "Edward Hardy", Popularity: 50}}); err != nil {
+			t.Fatalf("UpsertPeople failed: %v", err)
+		}
+		if got := people("tom"); slices.Contains(got, 2) {
+			t.Errorf("expected the old name to be gone, got %v", got)
+		}
+		if got := people("edward"); !slices.Equal(got, []int{2}) {
+			t.Errorf("expected the new name to be found, got %v", got)
+		}
+	})
+
+	t.Run("Rebuilt", func(t *testing.T) {
+		// Like a binary without FTS5 leaves the database behind
+		for _, trigger := range searchTriggers {
+			if _, err := db.conn.Exec("DROP TRIGGER " + trigger); err != nil {
+				t.Fatalf("DROP TRIGGER failed: %v", err)
+			}
+		}
+		if err := db.UpsertPeople([]Person{{ID: 3, Name: "Florence Pugh", Popularity: 50}}); err != nil {
+			t.Fatalf("UpsertPeople failed: %v", err)
+		}
+
+		if err := db.updateSearchIndex(); err != nil {
+			t.Fatalf("updateSearchIndex failed: %v", err)
+		}
+		if got := people("florence"); !slices.Equal(got, []int{3}) {
+			t.Errorf("expected the index to be rebuilt, got %v", got)
+		}
+	})
+
+	t.Run("Movies", func(t *testing.T) {
+		results, err := db.SearchMovies("hunt", 0)
+		if err != nil {
+			t.Fatalf("SearchMovies failed: %v", err)
+		}
+		var ids []int
+		for _, result := range results {
+			ids = append(ids, result.ID)
+		}
+		// The exact title first, then by popularity
+		if !slices.Equal(ids, []int{11, 12, 10}) {
+			t.Errorf("expected [11 12 10], got %v", ids)
+		}
+
+		results, err = db.SearchMovies("jagten", 1)
+		if err != nil {
+			t.Fatalf("SearchMovies failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != 10 || results[0].Match != SearchMatchAlias {
+			t.Errorf("expected The Hunt by its original title, got %+v", results)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package tmdbankigenerator
+
+import (
+	"fmt"
+)
+
+// DatasetStats describes what the database holds and what it lacks.
+type DatasetStats struct {
+	Tables           []TableCount    `json:"tables"`
+	CreditsByJobType []JobTypeCount  `json:"credits_by_job_type"`
+	MoviesByLanguage []LanguageCount `json:"movies_by_language"`
+	MoviesByDecade   []DecadeCount   `json:"movies_by_decade"`
+	TopPeople        []PersonCredits `json:"top_people"`
+	Gaps             DataQualityGaps `json:"gaps"`
+}
+
+type JobTypeCount struct {
+	JobType JobType `json:"job_type" db:"job_type"`
+	Credits int     `json:"credits" db:"credits"`
+}
+
+type LanguageCount struct {
+	// Empty for movies without a language
+	Language Language `json:"language" db:"language"`
+	Movies   int      `json:"movies" db:"movies"`
+}
+
+type DecadeCount struct {
+	// First year of the decade, e.g. 1990
+	Decade int `json:"decade" db:"decade"`
+	Movies int `json:"movies" db:"movies"`
+}
+
+// PersonCredits is how many credits a person has, on how many movies.
+type PersonCredits struct {
+	ID      int    `json:"id" db:"id"`
+	Name    string `json:"name" db:"name"`
+	Movies  int    `json:"movies" db:"movies"`
+	Credits int    `json:"credits" db:"credits"`
+}
+
+// DataQualityGaps counts rows missing data the notes show.
+type DataQualityGaps struct {
+	MoviesWithoutReleaseDate int `json:"movies_without_release_date"`
+	MoviesWithoutPoster      int `json:"movies_without_poster"`
+	PeopleWithoutImages      int `json:"people_without_images"`
+}
+
+// missingReleaseDate matches movies without a release date. Movies TMDB has
+// no date for are stored with the zero time.
+const missingReleaseDate = "(release_date IS NULL OR release_date = '' OR release_date LIKE '0001-01-01%')"
+
+// Stats reports on the dataset, with the topPeople most credited people.
+func (d *Database) Stats(topPeople int) (*DatasetStats, error) {
+	stats := &DatasetStats{
+		CreditsByJobType: []JobTypeCount{},
+		MoviesByLanguage: []LanguageCount{},
+		MoviesByDecade:   []DecadeCount{},
+		TopPeople:        []PersonCredits{},
+	}
+
+	var err error
+	if stats.Tables, err = d.TableCounts(); err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		name  string
+		dest  any
+		query string
+		args  []any
+	}{
+		{"credits by job type", &stats.CreditsByJobType, `
+    SELECT job_type, COUNT(*) AS credits
+    FROM credits
+    GROUP BY job_type
+    ORDER BY credits DESC, job_type
+    `, nil},
+		{"movies by language", &stats.MoviesByLanguage, `
+    SELECT COALESCE(language, '') AS language, COUNT(*) AS movies
+    FROM movies
+    GROUP BY 1
+    ORDER BY movies DESC, language
+    `, nil},
+		{"movies by decade", &stats.MoviesByDecade, `
+    SELECT CAST(substr(release_date, 1, 4) AS INTEGER) / 10 * 10 AS decade, COUNT(*) AS movies
+    FROM movies
+    WHERE NOT ` + missingReleaseDate + `
+    GROUP BY decade
+    ORDER BY decade
+    `, nil},
+		{"most credited people", &stats.TopPeople, `
+    SELECT p.id, COALESCE(p.name, '') AS name, COUNT(DISTINCT c.movie_id) AS movies, COUNT(*) AS credits
+    FROM credits c
+        INNER JOIN persons p ON p.id = c.person_id
+    GROUP BY p.id
+    ORDER BY movies DESC, credits DESC, p.id
+    LIMIT ?
+    `, []any{max(topPeople, 0)}},
+	}
+	for _, q := range queries {
+		if err := d.conn.Select(q.dest, q.query, q.args...); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", q.name, err)
+		}
+	}
+
+	gaps := []struct {
+		name  string
+		dest  *int
+		query string
+	}{
+		{"movies without release date", &stats.Gaps.MoviesWithoutReleaseDate, "SELECT COUNT(*) FROM movies WHERE " + missingReleaseDate},
+		{"movies without poster", &stats.Gaps.MoviesWithoutPoster, `
+    SELECT COUNT(*) FROM movies m
+    WHERE NOT EXISTS (SELECT 1 FROM movie_images i WHERE i.movie_id = m.id AND i.path != '')
+    `},
+		{"people without images", &stats.Gaps.PeopleWithoutImages, `
+    SELECT COUNT(*) FROM persons p
+    WHERE NOT EXISTS (SELECT 1 FROM person_images i WHERE i.person_id = p.id AND i.path != '')
+    `},
+	}
+	for _, gap := range gaps {
+		if err := d.conn.Get(gap.dest, gap.query); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", gap.name, err)
+		}
+	}
+
+	return stats, nil
+}
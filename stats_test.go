@@ -0,0 +1,48 @@
+package tmdbankigenerator
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	db := newTestQueryDatabase(t)
+	if err := db.UpsertMovies([]Movie{{ID: 5, Title: "Undated", Images: []MovieImage{{MovieID: 5, Path: ""}}}}); err != nil {
+		t.Fatalf("UpsertMovies failed: %v", err)
+	}
+
+	stats, err := db.Stats(2)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+
+	if i := slices.IndexFunc(stats.Tables, func(count TableCount) bool { return count.Table == "movies" }); i < 0 || stats.Tables[i].Rows != 5 {
+		t.Errorf("expected 5 movies, got %v", stats.Tables)
+	}
+
+	wantJobTypes := []JobTypeCount{{JobTypeCast, 6}, {JobTypeDirector, 2}}
+	if !slices.Equal(stats.CreditsByJobType, wantJobTypes) {
+		t.Errorf("expected credits by job type %v, got %v", wantJobTypes, stats.CreditsByJobType)
+	}
+
+	wantLanguages := []LanguageCount{{LanguageEnglish, 3}, {"", 1}, {LanguageDanish, 1}}
+	if !slices.Equal(stats.MoviesByLanguage, wantLanguages) {
+		t.Errorf("expected movies by language %v, got %v", wantLanguages, stats.MoviesByLanguage)
+	}
+
+	wantDecades := []DecadeCount{{1980, 1}, {2000, 2}, {2010, 1}}
+	if !slices.Equal(stats.MoviesByDecade, wantDecades) {
+		t.Errorf("expected movies by decade %v, got %v", wantDecades, stats.MoviesByDecade)
+	}
+
+	// Lead has two credits on Old English, Extra and the others one each
+	wantPeople := []PersonCredits{{ID: 10, Name: "Lead", Movies: 3, Credits: 4}, {ID: 11, Name: "Extra", Movies: 2, Credits: 2}}
+	if !slices.Equal(stats.TopPeople, wantPeople) {
+		t.Errorf("expected top people %v, got %v", wantPeople, stats.TopPeople)
+	}
+
+	wantGaps := DataQualityGaps{MoviesWithoutReleaseDate: 1, MoviesWithoutPoster: 4, PeopleWithoutImages: 3}
+	if stats.Gaps != wantGaps {
+		t.Errorf("expected gaps %+v, got %+v", wantGaps, stats.Gaps)
+	}
+}
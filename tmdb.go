@@ -5,8 +5,10 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"net/http"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	tmdb "github.com/cyruzin/golang-tmdb"
@@ -14,31 +16,49 @@ import (
 )
 
 type TMDbClient struct {
-	client *tmdb.Client
+	apiKey  string
+	baseURL string
+	http    *http.Client
+	cache   *ResponseCache
 
 	timeLimit *rate.Limiter
-}
-
-func (c *TMDbClient) Wait() {
-	c.WaitN(1)
-}
 
-func (c *TMDbClient) WaitN(n int) {
-	c.timeLimit.WaitN(context.Background(), n)
+	// Requests sent so far and how many may be sent, 0 is unlimited
+	requests      atomic.Int64
+	requestBudget int
 }
 
-func NewTMDbClient(apiKey string) (*TMDbClient, error) {
-	client, err := tmdb.Init(apiKey)
-	if err != nil {
-		return nil, err
+// NewTMDbClient returns a client for the TMDB API at baseURL, which is
+// DefaultTMDbBaseURL unless testing against a fake.
+func NewTMDbClient(apiKey string, baseURL string) (*TMDbClient, error) {
+	if apiKey == "" {
+		return nil, errors.New("api key is empty")
+	}
+	if baseURL == "" {
+		baseURL = DefaultTMDbBaseURL
 	}
 
 	return &TMDbClient{
-		client:    client,
+		apiKey:    apiKey,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		http:      &http.Client{Timeout: 10 * time.Second},
 		timeLimit: rate.NewLimiter(rate.Every(time.Second/30), 30),
 	}, nil
 }
 
+// SetRequestBudget limits how many requests the client sends, retries
+// included. Requests beyond it fail with ErrRequestBudgetExhausted. 0 is
+// unlimited.
+func (c *TMDbClient) SetRequestBudget(budget int) {
+	c.requestBudget = budget
+}
+
+// SetCache makes the client answer from cache where it can. A nil cache
+// fetches everything from TMDB.
+func (c *TMDbClient) SetCache(cache *ResponseCache) {
+	c.cache = cache
+}
+
 const minVoteCount = 10
 
 var disallowedGenres = []int64{99 /* Documentaries */, 10402 /* Music */}
@@ -47,10 +67,17 @@ var disallowedGenresMap = map[int64]struct{}{
 	10402: struct{}{},
 }
 
-func (c *TMDbClient) GetAllCreditsByPersonID(personId int) (Person, []Credit, error) {
-	c.Wait()
-	tmdbPerson, err := c.client.GetPersonDetails(personId, map[string]string{
+// GetAllCreditsByPersonID returns the person with their credits on movies
+// that pass the vote count, genre and short film filters. Movies TMDB no
+// longer knows are skipped.
+func (c *TMDbClient) GetAllCreditsByPersonID(ctx context.Context, personId int) (Person, []Credit, error) {
+	endpoint := fmt.Sprintf("person/%d", personId)
+	options := map[string]string{
 		"append_to_response": "movie_credits,images",
+	}
+	tmdbPerson, err := cachedFetch(c.cache, responseCacheKey(endpoint, options), func() (*tmdb.PersonDetails, error) {
+		var person tmdb.PersonDetails
+		return &person, c.get(ctx, endpoint, options, &person)
 	})
 	if err != nil {
 		return Person{}, nil, err
@@ -66,7 +93,10 @@ func (c *TMDbClient) GetAllCreditsByPersonID(personId int) (Person, []Credit, er
 			continue
 		}
 
-		movie, keywords, err := c.GetMovie(int(credit.ID))
+		movie, keywords, err := c.GetMovie(ctx, int(credit.ID))
+		if IsTMDbNotFound(err) {
+			continue
+		}
 		if err != nil {
 			return Person{}, nil, err
 		}
@@ -77,10 +107,13 @@ func (c *TMDbClient) GetAllCreditsByPersonID(personId int) (Person, []Credit, er
 		}
 
 		credits = append(credits, Credit{
-			PersonID: personId,
-			MovieID:  int(credit.ID),
-			JobType:  JobTypeCast,
-			Movie:    &movie,
+			PersonID:  personId,
+			MovieID:   int(credit.ID),
+			JobType:   JobTypeCast,
+			CastOrder: credit.Order,
+			Character: credit.Character,
+			CreditID:  credit.CreditID,
+			Movie:     &movie,
 		})
 	}
 
@@ -95,7 +128,10 @@ func (c *TMDbClient) GetAllCreditsByPersonID(personId int) (Person, []Credit, er
 			continue
 		}
 
-		movie, keywords, err := c.GetMovie(int(credit.ID))
+		movie, keywords, err := c.GetMovie(ctx, int(credit.ID))
+		if IsTMDbNotFound(err) {
+			continue
+		}
 		if err != nil {
 			return Person{}, nil, err
 		}
@@ -106,10 +142,13 @@ func (c *TMDbClient) GetAllCreditsByPersonID(personId int) (Person, []Credit, er
 		}
 
 		credits = append(credits, Credit{
-			PersonID: personId,
-			MovieID:  int(credit.ID),
-			JobType:  jobType,
-			Movie:    &movie,
+			PersonID:   personId,
+			MovieID:    int(credit.ID),
+			JobType:    jobType,
+			CreditID:   credit.CreditID,
+			Department: credit.Department,
+			Job:        credit.Job,
+			Movie:      &movie,
 		})
 	}
 
@@ -134,15 +173,34 @@ func (c *TMDbClient) GetAllCreditsByPersonID(personId int) (Person, []Credit, er
 	}, credits, nil
 }
 
-func (c *TMDbClient) GetMovieDetails(id int, urlOptions map[string]string) (*tmdb.MovieDetails, error) {
-	c.Wait()
-	return c.client.GetMovieDetails(id, urlOptions)
+// GetPersonAliases returns the other names TMDB knows a person by, which the
+// credits of a movie leave out.
+func (c *TMDbClient) GetPersonAliases(ctx context.Context, personId int) ([]string, error) {
+	endpoint := fmt.Sprintf("person/%d", personId)
+	tmdbPerson, err := cachedFetch(c.cache, responseCacheKey(endpoint, nil), func() (*tmdb.PersonDetails, error) {
+		var person tmdb.PersonDetails
+		return &person, c.get(ctx, endpoint, nil, &person)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if tmdbPerson.AlsoKnownAs == nil {
+		return []string{}, nil
+	}
+	return tmdbPerson.AlsoKnownAs, nil
 }
 
-func (c *TMDbClient) GetMovie(movieId int) (Movie, tmdb.MovieKeywords, error) {
-	c.Wait()
+func (c *TMDbClient) GetMovieDetails(ctx context.Context, id int, urlOptions map[string]string) (*tmdb.MovieDetails, error) {
+	endpoint := fmt.Sprintf("movie/%d", id)
+	return cachedFetch(c.cache, responseCacheKey(endpoint, urlOptions), func() (*tmdb.MovieDetails, error) {
+		var movie tmdb.MovieDetails
+		return &movie, c.get(ctx, endpoint, urlOptions, &movie)
+	})
+}
 
-	tmdbMovie, err := c.client.GetMovieDetails(movieId, map[string]string{
+func (c *TMDbClient) GetMovie(ctx context.Context, movieId int) (Movie, tmdb.MovieKeywords, error) {
+	tmdbMovie, err := c.GetMovieDetails(ctx, movieId, map[string]string{
 		"append_to_response": "keywords",
 	})
 	if err != nil {
@@ -180,7 +238,7 @@ func IsDisallowedGenre(genres []struct {
 }) bool {
 	for _, genre := range genres {
 		if _, ok := disallowedGenresMap[genre.ID]; ok {
-			fmt.Printf("Has dissallowed genre: %d\n", genre)
+			fmt.Printf("Has dissallowed genre: %d\n", genre.ID)
 			return true
 		}
 	}
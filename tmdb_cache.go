@@ -0,0 +1,98 @@
+package tmdbankigenerator
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type cachedResponse struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// ResponseCache keeps TMDB responses on disk, keyed by endpoint and
+// parameters, so fetching the same movie or person again is free.
+type ResponseCache struct {
+	cache *Cache[string, cachedResponse]
+	// Responses older than this are fetched again, 0 keeps them forever
+	ttl time.Duration
+}
+
+// OpenResponseCache opens the cache at path. Entries are dropped from the
+// file once they are older than ttl, and the least recently used are dropped
+// beyond maxEntries; 0 disables either.
+func OpenResponseCache(path string, ttl time.Duration, maxEntries int) (*ResponseCache, error) {
+	cache, err := NewCacheWithOptions[string, cachedResponse](path, CacheOptions{
+		TTL:        ttl,
+		MaxEntries: maxEntries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseCache{
+		cache: cache,
+		ttl:   ttl,
+	}, nil
+}
+
+// Close writes the cache to disk.
+func (c *ResponseCache) Close() error {
+	return c.cache.Close()
+}
+
+// responseCacheKey builds the cache key of a request, e.g.
+// "movie/550?append_to_response=credits,keywords". Parameters are sorted so
+// the key doesn't depend on map order.
+func responseCacheKey(endpoint string, params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var sb strings.Builder
+	sb.WriteString(endpoint)
+	for i, key := range keys {
+		if i == 0 {
+			sb.WriteByte('?')
+		} else {
+			sb.WriteByte('&')
+		}
+		fmt.Fprintf(&sb, "%s=%s", key, params[key])
+	}
+
+	return sb.String()
+}
+
+// cachedFetch returns the cached response for key if there is a fresh one,
+// and calls fetch and caches its result otherwise. A nil cache always
+// fetches.
+func cachedFetch[T any](c *ResponseCache, key string, fetch func() (T, error)) (T, error) {
+	if c == nil {
+		return fetch()
+	}
+
+	if ok, cached := c.cache.Get(key); ok && (c.ttl == 0 || time.Since(cached.FetchedAt) < c.ttl) {
+		var value T
+		if err := json.Unmarshal(cached.Body, &value); err == nil {
+			return value, nil
+		}
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		return value, fmt.Errorf("failed to cache %s: %w", key, err)
+	}
+	c.cache.Set(key, cachedResponse{FetchedAt: time.Now(), Body: body})
+
+	return value, nil
+}
@@ -0,0 +1,91 @@
+package tmdbankigenerator
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	got := responseCacheKey("movie/550", map[string]string{
+		"language":           "en-US",
+		"append_to_response": "keywords,credits",
+	})
+	want := "movie/550?append_to_response=keywords,credits&language=en-US"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if got := responseCacheKey("person/1", nil); got != "person/1" {
+		t.Errorf("expected %q, got %q", "person/1", got)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tmdb_cache.json")
+
+	fetches := 0
+	fetch := func() (*Movie, error) {
+		fetches++
+		return &Movie{ID: 550, Title: "Fight Club"}, nil
+	}
+
+	cache, err := OpenResponseCache(path, 0, 0)
+	if err != nil {
+		t.Fatalf("OpenResponseCache failed: %v", err)
+	}
+
+	for range 2 {
+		movie, err := cachedFetch(cache, "movie/550", fetch)
+		if err != nil {
+			t.Fatalf("cachedFetch failed: %v", err)
+		}
+		if movie.Title != "Fight Club" {
+			t.Errorf("expected Fight Club, got %q", movie.Title)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("expected 1 fetch, got %d", fetches)
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	t.Run("Reopened", func(t *testing.T) {
+		cache, err := OpenResponseCache(path, 0, 0)
+		if err != nil {
+			t.Fatalf("OpenResponseCache failed: %v", err)
+		}
+
+		if _, err := cachedFetch(cache, "movie/550", fetch); err != nil {
+			t.Fatalf("cachedFetch failed: %v", err)
+		}
+		if fetches != 1 {
+			t.Errorf("expected the saved response to be used, got %d fetches", fetches)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		cache, err := OpenResponseCache(path, time.Nanosecond, 0)
+		if err != nil {
+			t.Fatalf("OpenResponseCache failed: %v", err)
+		}
+
+		if _, err := cachedFetch(cache, "movie/550", fetch); err != nil {
+			t.Fatalf("cachedFetch failed: %v", err)
+		}
+		if fetches != 2 {
+			t.Errorf("expected the expired response to be fetched again, got %d fetches", fetches)
+		}
+	})
+
+	t.Run("Bypassed", func(t *testing.T) {
+		if _, err := cachedFetch(nil, "movie/550", fetch); err != nil {
+			t.Fatalf("cachedFetch failed: %v", err)
+		}
+		if fetches != 3 {
+			t.Errorf("expected a nil cache to always fetch, got %d fetches", fetches)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package tmdbankigenerator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultTMDbBaseURL = "https://api.themoviedb.org/3"
+
+const (
+	// maxRetries is how often a request is retried after a 429, a 5xx or a
+	// network error before giving up
+	maxRetries = 6
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// ErrRequestBudgetExhausted is returned once the client sent as many
+// requests as its budget allows.
+var ErrRequestBudgetExhausted = errors.New("tmdb request budget exhausted")
+
+// TMDbNotFoundError is returned when TMDB doesn't know the requested movie or
+// person, e.g. because it was deleted after the export was made. Callers
+// usually skip it.
+type TMDbNotFoundError struct {
+	Endpoint string
+}
+
+func (e *TMDbNotFoundError) Error() string {
+	return fmt.Sprintf("tmdb: %s not found", e.Endpoint)
+}
+
+// IsTMDbNotFound reports whether err is, or wraps, a TMDbNotFoundError.
+func IsTMDbNotFound(err error) bool {
+	var notFound *TMDbNotFoundError
+	return errors.As(err, &notFound)
+}
+
+type TMDbStatusError struct {
+	Endpoint   string
+	StatusCode int
+	Message    string
+}
+
+func (e *TMDbStatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("tmdb: %s: %d %s", e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("tmdb: %s: %d %s", e.Endpoint, e.StatusCode, e.Message)
+}
+
+func (e *TMDbStatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// get fetches the endpoint, e.g. "movie/550", and decodes the response into
+// v. Rate limited, failed and 5xx requests are retried with jittered
+// exponential backoff, waiting at least as long as Retry-After asks.
+func (c *TMDbClient) get(ctx context.Context, endpoint string, params map[string]string, v any) error {
+	query := url.Values{}
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	query.Set("api_key", c.apiKey)
+	requestURL := fmt.Sprintf("%s/%s?%s", c.baseURL, endpoint, query.Encode())
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.do(ctx, endpoint, requestURL, v)
+		if err == nil {
+			return nil
+		}
+
+		var statusErr *TMDbStatusError
+		retryable := isNetworkError(err)
+		if errors.As(err, &statusErr) {
+			retryable = statusErr.retryable()
+		}
+		if !retryable || ctx.Err() != nil || attempt >= maxRetries {
+			return err
+		}
+
+		wait := max(backoff(attempt), retryAfter)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// do sends a single request. It returns how long the server asked to wait
+// before retrying, if it did.
+func (c *TMDbClient) do(ctx context.Context, endpoint, requestURL string, v any) (time.Duration, error) {
+	if n := c.requests.Add(1); c.requestBudget > 0 && n > int64(c.requestBudget) {
+		c.requests.Add(-1)
+		return 0, ErrRequestBudgetExhausted
+	}
+
+	if err := c.timeLimit.Wait(ctx); err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("tmdb: %s: %w", endpoint, err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusOK:
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			return 0, fmt.Errorf("tmdb: %s: failed to decode response: %w", endpoint, err)
+		}
+		return 0, nil
+	case res.StatusCode == http.StatusNotFound:
+		return 0, &TMDbNotFoundError{Endpoint: endpoint}
+	}
+
+	var body struct {
+		StatusMessage string `json:"status_message"`
+	}
+	json.NewDecoder(io.LimitReader(res.Body, 64*1024)).Decode(&body)
+
+	return parseRetryAfter(res.Header.Get("Retry-After")), &TMDbStatusError{
+		Endpoint:   endpoint,
+		StatusCode: res.StatusCode,
+		Message:    body.StatusMessage,
+	}
+}
+
+// Requests is how many requests the client sent so far, retries included.
+func (c *TMDbClient) Requests() int {
+	return int(c.requests.Load())
+}
+
+// backoff is the jittered wait before the given retry: somewhere between half
+// and all of an exponentially growing delay.
+func backoff(attempt int) time.Duration {
+	delay := min(minBackoff<<attempt, maxBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date. It returns 0 if there is none.
+func parseRetryAfter(header string) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
+
+// isNetworkError reports whether the request failed before TMDB answered,
+// e.g. a timeout or a dropped connection.
+func isNetworkError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,108 @@
+package tmdbankigenerator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestTMDbClient(t *testing.T, handler http.HandlerFunc) *TMDbClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewTMDbClient("test", server.URL)
+	if err != nil {
+		t.Fatalf("NewTMDbClient failed: %v", err)
+	}
+
+	return client
+}
+
+func TestTMDbClientRetry(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestTMDbClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"id": 550, "title": "Fight Club"}`))
+	})
+
+	movie, err := client.GetMovieDetails(context.Background(), 550, nil)
+	if err != nil {
+		t.Fatalf("GetMovieDetails failed: %v", err)
+	}
+	if movie.Title != "Fight Club" {
+		t.Errorf("expected Fight Club, got %q", movie.Title)
+	}
+	if client.Requests() != 2 {
+		t.Errorf("expected 2 requests, got %d", client.Requests())
+	}
+}
+
+func TestTMDbClientNotFound(t *testing.T) {
+	client := newTestTMDbClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status_code": 34, "status_message": "The resource you requested could not be found."}`))
+	})
+
+	_, err := client.GetMovieDetails(context.Background(), 1, nil)
+	if !IsTMDbNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	if client.Requests() != 1 {
+		t.Errorf("expected a not found error not to be retried, got %d requests", client.Requests())
+	}
+}
+
+func TestTMDbClientRequestBudget(t *testing.T) {
+	client := newTestTMDbClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 550}`))
+	})
+	client.SetRequestBudget(1)
+
+	if _, err := client.GetMovieDetails(context.Background(), 550, nil); err != nil {
+		t.Fatalf("GetMovieDetails failed: %v", err)
+	}
+	if _, err := client.GetMovieDetails(context.Background(), 551, nil); !errors.Is(err, ErrRequestBudgetExhausted) {
+		t.Errorf("expected the budget to be exhausted, got %v", err)
+	}
+}
+
+func TestTMDbClientCancel(t *testing.T) {
+	client := newTestTMDbClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetMovieDetails(ctx, 550, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected retrying to stop with the context, took %s", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("expected 3s, got %s", got)
+	}
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("expected 0, got %s", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 0 || got > time.Minute {
+		t.Errorf("expected up to a minute, got %s", got)
+	}
+}
@@ -0,0 +1,382 @@
+// Package tmdbtest provides a fake TMDB API for tests, serving movies and
+// people added to it the way api.themoviedb.org/3 serves them.
+package tmdbtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Genre struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+var (
+	GenreDrama       = Genre{ID: 18, Name: "Drama"}
+	GenreThriller    = Genre{ID: 53, Name: "Thriller"}
+	GenreComedy      = Genre{ID: 35, Name: "Comedy"}
+	GenreDocumentary = Genre{ID: 99, Name: "Documentary"}
+	GenreMusic       = Genre{ID: 10402, Name: "Music"}
+)
+
+type Collection struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	PosterPath string `json:"poster_path"`
+}
+
+type Company struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	OriginCountry string `json:"origin_country"`
+}
+
+type Country struct {
+	Code string `json:"iso_3166_1"`
+	Name string `json:"name"`
+}
+
+type CastMember struct {
+	ID                 int64   `json:"id"`
+	Name               string  `json:"name"`
+	Character          string  `json:"character"`
+	Order              int     `json:"order"`
+	CreditID           string  `json:"credit_id"`
+	Popularity         float32 `json:"popularity"`
+	Gender             int     `json:"gender"`
+	KnownForDepartment string  `json:"known_for_department"`
+	ProfilePath        string  `json:"profile_path"`
+	Adult              bool    `json:"adult"`
+}
+
+type CrewMember struct {
+	ID                 int64   `json:"id"`
+	Name               string  `json:"name"`
+	Job                string  `json:"job"`
+	Department         string  `json:"department"`
+	CreditID           string  `json:"credit_id"`
+	Popularity         float32 `json:"popularity"`
+	Gender             int     `json:"gender"`
+	KnownForDepartment string  `json:"known_for_department"`
+	ProfilePath        string  `json:"profile_path"`
+	Adult              bool    `json:"adult"`
+}
+
+type Movie struct {
+	ID               int64   `json:"id"`
+	Title            string  `json:"title"`
+	OriginalTitle    string  `json:"original_title"`
+	OriginalLanguage string  `json:"original_language"`
+	Overview         string  `json:"overview"`
+	Tagline          string  `json:"tagline"`
+	ReleaseDate      string  `json:"release_date"`
+	Runtime          int     `json:"runtime"`
+	Popularity       float32 `json:"popularity"`
+	VoteCount        int64   `json:"vote_count"`
+	VoteAverage      float32 `json:"vote_average"`
+	Adult            bool    `json:"adult"`
+	PosterPath       string  `json:"poster_path"`
+	IMDbID           string  `json:"imdb_id"`
+	Budget           int64   `json:"budget"`
+	Revenue          int64   `json:"revenue"`
+	Genres           []Genre `json:"genres"`
+
+	BelongsToCollection *Collection `json:"belongs_to_collection"`
+	ProductionCompanies []Company   `json:"production_companies"`
+	ProductionCountries []Country   `json:"production_countries"`
+
+	// Served with append_to_response=keywords
+	Keywords []string `json:"-"`
+	// Served with append_to_response=credits, and as the movie credits of
+	// the people in them
+	Cast []CastMember `json:"-"`
+	Crew []CrewMember `json:"-"`
+}
+
+type Person struct {
+	ID                 int64    `json:"id"`
+	Name               string   `json:"name"`
+	AlsoKnownAs        []string `json:"also_known_as"`
+	KnownForDepartment string   `json:"known_for_department"`
+	Popularity         float32  `json:"popularity"`
+	Gender             int      `json:"gender"`
+	Birthday           string   `json:"birthday"`
+	PlaceOfBirth       string   `json:"place_of_birth"`
+	ProfilePath        string   `json:"profile_path"`
+	Adult              bool     `json:"adult"`
+	IMDbID             string   `json:"imdb_id"`
+}
+
+// Server is a fake TMDB API. Movies and people that weren't added are
+// answered with 404, like TMDB answers IDs it doesn't know.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	movies   map[int64]Movie
+	people   map[int64]Person
+	failures map[string][]int
+	requests map[string]int
+}
+
+// NewServer starts a fake TMDB API. Point the client at its URL and close it
+// when done.
+func NewServer() *Server {
+	s := &Server{
+		movies:   make(map[int64]Movie),
+		people:   make(map[int64]Person),
+		failures: make(map[string][]int),
+		requests: make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /movie/{id}", s.serveMovie)
+	mux.HandleFunc("GET /person/{id}", s.servePerson)
+	s.Server = httptest.NewServer(s.middleware(mux))
+
+	return s
+}
+
+func (s *Server) AddMovies(movies ...Movie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, movie := range movies {
+		s.movies[movie.ID] = movie
+	}
+}
+
+func (s *Server) AddPeople(people ...Person) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, person := range people {
+		s.people[person.ID] = person
+	}
+}
+
+// FailNext makes the next requests to path, e.g. "/movie/550", fail with the
+// given statuses, one per request. 429s ask to retry right away.
+func (s *Server) FailNext(path string, statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[path] = append(s.failures[path], statuses...)
+}
+
+// Requests is how many requests were made to path, failed ones included.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[path]
+}
+
+// TotalRequests is how many requests were made to the server.
+func (s *Server) TotalRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for _, n := range s.requests {
+		total += n
+	}
+	return total
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		var status int
+		if failures := s.failures[r.URL.Path]; len(failures) > 0 {
+			status, s.failures[r.URL.Path] = failures[0], failures[1:]
+		}
+		s.mu.Unlock()
+
+		if r.URL.Query().Get("api_key") == "" {
+			writeError(w, http.StatusUnauthorized, 7, "Invalid API key: You must be granted a valid key.")
+			return
+		}
+
+		switch status {
+		case 0:
+			next.ServeHTTP(w, r)
+		case http.StatusTooManyRequests:
+			w.Header().Set("Retry-After", "0")
+			writeError(w, status, 25, "Your request count is over the allowed limit.")
+		case http.StatusNotFound:
+			writeNotFound(w)
+		default:
+			writeError(w, status, 11, "Internal error: Something went wrong, contact TMDb.")
+		}
+	})
+}
+
+func (s *Server) serveMovie(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	s.mu.Lock()
+	movie, ok := s.movies[id]
+	s.mu.Unlock()
+
+	if err != nil || !ok {
+		writeNotFound(w)
+		return
+	}
+
+	response := map[string]any{}
+	merge(response, movie)
+
+	appended := appendToResponse(r)
+	if slices.Contains(appended, "keywords") {
+		keywords := make([]map[string]any, len(movie.Keywords))
+		for i, name := range movie.Keywords {
+			keywords[i] = map[string]any{"id": i + 1, "name": name}
+		}
+		response["keywords"] = map[string]any{"id": movie.ID, "keywords": keywords}
+	}
+	if slices.Contains(appended, "credits") {
+		response["credits"] = map[string]any{
+			"id":   movie.ID,
+			"cast": nonNil(movie.Cast),
+			"crew": nonNil(movie.Crew),
+		}
+	}
+
+	writeJSON(w, response)
+}
+
+func (s *Server) servePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	person, ok := s.people[id]
+	if err != nil || !ok {
+		writeNotFound(w)
+		return
+	}
+
+	response := map[string]any{}
+	merge(response, person)
+
+	appended := appendToResponse(r)
+	if slices.Contains(appended, "movie_credits") {
+		response["movie_credits"] = s.movieCredits(id)
+	}
+	if slices.Contains(appended, "images") {
+		profiles := []map[string]any{}
+		if person.ProfilePath != "" {
+			profiles = append(profiles, map[string]any{"file_path": person.ProfilePath})
+		}
+		response["images"] = map[string]any{"id": id, "profiles": profiles}
+	}
+
+	writeJSON(w, response)
+}
+
+// movieCredits lists the movies the person is in the cast or crew of. s.mu
+// must be held.
+func (s *Server) movieCredits(personID int64) map[string]any {
+	cast := []map[string]any{}
+	crew := []map[string]any{}
+
+	ids := make([]int64, 0, len(s.movies))
+	for id := range s.movies {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		movie := s.movies[id]
+
+		credit := func() map[string]any {
+			genreIDs := make([]int64, len(movie.Genres))
+			for i, genre := range movie.Genres {
+				genreIDs[i] = genre.ID
+			}
+			return map[string]any{
+				"id":                movie.ID,
+				"title":             movie.Title,
+				"original_title":    movie.OriginalTitle,
+				"original_language": movie.OriginalLanguage,
+				"release_date":      movie.ReleaseDate,
+				"popularity":        movie.Popularity,
+				"vote_count":        movie.VoteCount,
+				"vote_average":      movie.VoteAverage,
+				"adult":             movie.Adult,
+				"poster_path":       movie.PosterPath,
+				"genre_ids":         genreIDs,
+			}
+		}
+
+		for _, member := range movie.Cast {
+			if member.ID == personID {
+				c := credit()
+				c["character"] = member.Character
+				c["order"] = member.Order
+				c["credit_id"] = member.CreditID
+				cast = append(cast, c)
+			}
+		}
+		for _, member := range movie.Crew {
+			if member.ID == personID {
+				c := credit()
+				c["job"] = member.Job
+				c["department"] = member.Department
+				c["credit_id"] = member.CreditID
+				crew = append(crew, c)
+			}
+		}
+	}
+
+	return map[string]any{"id": personID, "cast": cast, "crew": crew}
+}
+
+func appendToResponse(r *http.Request) []string {
+	value := r.URL.Query().Get("append_to_response")
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// merge adds the JSON fields of v to response.
+func merge(response map[string]any, v any) {
+	bytes, _ := json.Marshal(v)
+	json.Unmarshal(bytes, &response)
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, 34, "The resource you requested could not be found.")
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":        false,
+		"status_code":    code,
+		"status_message": message,
+	})
+}